@@ -0,0 +1,377 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CallParam is a parameter of the stored procedure passed to CallBuilder.
+type CallParam struct {
+	// Placeholder is the placeholder of the parameter in the dialect of DB, such as "?" and "$1",
+	// the user variable of the MySQL OUT parameters, or NULL for the PostgreSQL OUT parameters.
+	Placeholder string
+	// Out reports whether it's an OUT or INOUT parameter.
+	Out bool
+}
+
+// CallBuilder returns the statement calling the stored procedure with the parameters.
+type CallBuilder func(proc string, params []CallParam) string
+
+// CallStatement builds "call proc(?, @sqlw_out2)" for MySQL and "call proc($1, NULL)" for PostgreSQL.
+func CallStatement(proc string, params []CallParam) string {
+	placeholders := make([]string, len(params))
+	for i, param := range params {
+		placeholders[i] = param.Placeholder
+	}
+	return "call " + proc + "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// ExecStatement builds "exec proc @p1, @p2 OUTPUT" for SQL Server.
+func ExecStatement(proc string, params []CallParam) string {
+	placeholders := make([]string, len(params))
+	for i, param := range params {
+		placeholders[i] = fmt.Sprintf("@p%d", i+1)
+		if param.Out {
+			placeholders[i] += " OUTPUT"
+		}
+	}
+	return strings.TrimSpace("exec " + proc + " " + strings.Join(placeholders, ", "))
+}
+
+// BlockStatement builds "begin proc(:1, :2); end;" for Oracle.
+func BlockStatement(proc string, params []CallParam) string {
+	placeholders := make([]string, len(params))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf(":%d", i+1)
+	}
+	return "begin " + proc + "(" + strings.Join(placeholders, ", ") + "); end;"
+}
+
+func defaultCallBuilder(driverName string) CallBuilder {
+	switch {
+	case strings.Contains(driverName, "sqlserver") || strings.Contains(driverName, "mssql"):
+		return ExecStatement
+	case strings.Contains(driverName, "oracle") || strings.Contains(driverName, "godror") || strings.Contains(driverName, "oci8"):
+		return BlockStatement
+	default:
+		return CallStatement
+	}
+}
+
+// callOutMode is how the driver returns the OUT parameters.
+type callOutMode int
+
+const (
+	// callOutArgs binds the OUT parameters by sql.Out, such as SQL Server and Oracle.
+	callOutArgs callOutMode = iota
+	// callOutVars passes the OUT parameters as the user variables and selects them after the call,
+	// since the MySQL drivers don't support sql.Out.
+	callOutVars
+	// callOutRow passes NULL for the OUT parameters and maps the row of the OUT parameters returned
+	// by the call, which is how PostgreSQL procedures work.
+	callOutRow
+)
+
+func defaultCallOutMode(driverName string) callOutMode {
+	switch {
+	case strings.Contains(driverName, "mysql"):
+		return callOutVars
+	case strings.Contains(driverName, "postgres") || strings.Contains(driverName, "pgx") || driverName == "pq":
+		return callOutRow
+	default:
+		return callOutArgs
+	}
+}
+
+func (db *DB) CallBuilder() CallBuilder {
+	return db.callBuilder
+}
+
+func (db *DB) SetCallBuilder(callBuilder CallBuilder) {
+	db.callBuilder = callBuilder
+}
+
+// checkProcName returns an error unless proc is an identifier optionally qualified by dots,
+// the parts can be quoted by backticks, double quotes or brackets.
+func checkProcName(proc string) error {
+	for i := 0; ; i++ {
+		if i >= len(proc) {
+			return fmt.Errorf("[sqlw %v] invalid procedure name: %q", opTypCall, proc)
+		}
+		switch c := proc[i]; c {
+		case '`', '"', '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(proc[i+1:], closing)
+			if end <= 0 {
+				return fmt.Errorf("[sqlw %v] invalid procedure name: %q", opTypCall, proc)
+			}
+			i += end + 2
+		default:
+			j := i
+			for j < len(proc) && (isIdentChar(proc[j]) || proc[j] == '$') {
+				j++
+			}
+			if j == i || isDigit(proc[i]) {
+				return fmt.Errorf("[sqlw %v] invalid procedure name: %q", opTypCall, proc)
+			}
+			i = j
+		}
+		if i == len(proc) {
+			return nil
+		}
+		if proc[i] != '.' {
+			return fmt.Errorf("[sqlw %v] invalid procedure name: %q", opTypCall, proc)
+		}
+	}
+}
+
+type callParam struct {
+	name     string
+	pos      int
+	in       bool
+	out      bool
+	value    interface{} // the IN value
+	fieldIdx int         // the index of the OUT field
+	secret   bool
+	variable string // the MySQL user variable
+}
+
+// callParams returns the tagged fields of in followed by the tagged fields of out, the fields
+// with the "pos=N" tag option are placed at the Nth parameter and the others fill the rest in
+// order. The out fields with the "inout" tag option are also passed in.
+func callParams(db *DB, in, out interface{}) ([]*callParam, error) {
+	var params []*callParam
+	parse := func(structVal reflect.Value, isOut bool) error {
+		typ := structVal.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			strField := typ.Field(i)
+			fieldName := db.parseFieldName(&strField)
+			if fieldName == "" {
+				continue
+			}
+			opts := db.parseFieldOptions(&strField)
+			param := &callParam{name: fieldName, in: !isOut || opts.Contains("inout"), out: isOut, fieldIdx: i, secret: getSecretFields(db, typ)[i]}
+			if s, ok := opts.Get("pos"); ok {
+				pos, err := strconv.Atoi(s)
+				if err != nil || pos < 1 {
+					return fmt.Errorf("[sqlw %v] invalid pos option of %v.%v: %q", opTypCall, typ, strField.Name, s)
+				}
+				param.pos = pos
+			}
+			if param.in {
+				v, err := db.encodeField(structVal, i)
+				if err != nil {
+					return err
+				}
+				param.value = v
+			}
+			params = append(params, param)
+		}
+		return nil
+	}
+	if in != nil {
+		inVal := reflect.Indirect(reflect.ValueOf(in))
+		if inVal.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("[sqlw %v] invalid in type: %v", opTypCall, reflect.TypeOf(in))
+		}
+		if err := parse(inVal, false); err != nil {
+			return nil, err
+		}
+	}
+	if out != nil {
+		outTyp := reflect.TypeOf(out)
+		if !isStructPtr(outTyp) {
+			return nil, fmt.Errorf("[sqlw %v] invalid out type: %v", opTypCall, outTyp)
+		}
+		if err := parse(reflect.ValueOf(out).Elem(), true); err != nil {
+			return nil, err
+		}
+	}
+
+	ordered := make([]*callParam, len(params))
+	for _, param := range params {
+		if param.pos == 0 {
+			continue
+		}
+		if param.pos > len(params) || ordered[param.pos-1] != nil {
+			return nil, fmt.Errorf("[sqlw %v] invalid pos %v of parameter %v with %v parameters", opTypCall, param.pos, param.name, len(params))
+		}
+		ordered[param.pos-1] = param
+	}
+	i := 0
+	for _, param := range params {
+		if param.pos != 0 {
+			continue
+		}
+		for ordered[i] != nil {
+			i++
+		}
+		ordered[i] = param
+	}
+	return ordered, nil
+}
+
+// callQuery returns the statement, the args and the columns of the args of params, and the
+// OUT parameters passed as the MySQL user variables.
+func callQuery(db *DB, proc string, params []*callParam, outVal reflect.Value) (string, []interface{}, *argColumns, []*callParam) {
+	var args []interface{}
+	var vars []*callParam
+	cols := &argColumns{}
+	builderParams := make([]CallParam, len(params))
+	for i, param := range params {
+		switch {
+		case !param.out:
+			args = append(args, param.value)
+			cols.add(param.name, param.secret)
+			builderParams[i] = CallParam{Placeholder: db.placeholderBuilder(len(args))}
+		case db.callOutMode == callOutVars:
+			param.variable = fmt.Sprintf("@sqlw_out%d", i+1)
+			vars = append(vars, param)
+			builderParams[i] = CallParam{Placeholder: param.variable, Out: true}
+		case db.callOutMode == callOutRow:
+			builderParams[i] = CallParam{Placeholder: "NULL", Out: true}
+			if param.in {
+				args = append(args, param.value)
+				cols.add(param.name, param.secret)
+				builderParams[i].Placeholder = db.placeholderBuilder(len(args))
+			}
+		default:
+			args = append(args, sql.Out{Dest: outVal.Field(param.fieldIdx).Addr().Interface(), In: param.in})
+			cols.add(param.name, param.secret)
+			builderParams[i] = CallParam{Placeholder: db.placeholderBuilder(len(args)), Out: true}
+		}
+	}
+	return db.callBuilder(proc, builderParams), args, cols, vars
+}
+
+// callSelector is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type callSelector interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func callContext(db *DB, ctx context.Context, selector Selector, proc string, in, out interface{}, dsts ...interface{}) (Result, error) {
+	if err := checkProcName(proc); err != nil {
+		return newResult(db, nil, proc, nil, false), err
+	}
+	params, err := callParams(db, in, out)
+	if err != nil {
+		return newResult(db, nil, proc, nil, false), err
+	}
+	var outVal reflect.Value
+	if out != nil {
+		outVal = reflect.ValueOf(out).Elem()
+	}
+	query, args, cols, vars := callQuery(db, proc, params, outVal)
+	if db.callOutMode == callOutRow {
+		for _, param := range params {
+			if param.out {
+				// the first result set is the row of the OUT parameters
+				dsts = append([]interface{}{out}, dsts...)
+				break
+			}
+		}
+	}
+
+	info := newQueryInfo(selector, nil, opTypCall, query, args)
+	info.cols = cols
+	return db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		query, args := info.Query, info.Args
+		var s callSelector = selector
+		if len(vars) > 0 {
+			// the user variables live in the session
+			if sqlDB, ok := selector.(*sql.DB); ok {
+				conn, err := sqlDB.Conn(ctx)
+				if err != nil {
+					return newResult(db, nil, query, args, false), err
+				}
+				defer conn.Close()
+				s = conn
+			}
+			if err := setCallVars(ctx, s, vars); err != nil {
+				return newResult(db, nil, query, args, false), err
+			}
+		}
+
+		var result sql.Result
+		var notFound bool
+		var err error
+		if len(dsts) == 0 {
+			result, err = s.ExecContext(ctx, info.driverQuery, info.driverArgs...)
+		} else {
+			var rows *sql.Rows
+			rows, err = s.QueryContext(ctx, info.driverQuery, info.driverArgs...)
+			if err != nil {
+				return newResult(db, nil, query, args, false), err
+			}
+			defer rows.Close()
+			notFound, err = rowsToMulti(db, rows, info, dsts, query, emptyQueryOptions)
+			if err == nil {
+				// the drivers set the OUT parameters after the rows are consumed
+				err = rows.Close()
+			}
+		}
+		if err == nil && len(vars) > 0 {
+			err = selectCallVars(ctx, db, s, vars, outVal)
+		}
+		return newResult(db, result, query, args, notFound), err
+	})
+}
+
+// setCallVars sets the user variables of the INOUT parameters.
+func setCallVars(ctx context.Context, s callSelector, vars []*callParam) error {
+	var assigns []string
+	var args []interface{}
+	for _, param := range vars {
+		if param.in {
+			assigns = append(assigns, param.variable+" = ?")
+			args = append(args, param.value)
+		}
+	}
+	if len(assigns) == 0 {
+		return nil
+	}
+	_, err := s.ExecContext(ctx, "set "+strings.Join(assigns, ", "), args...)
+	return err
+}
+
+// selectCallVars selects the user variables of the OUT parameters into outVal.
+func selectCallVars(ctx context.Context, db *DB, s callSelector, vars []*callParam, outVal reflect.Value) error {
+	names := make([]string, len(vars))
+	for i, param := range vars {
+		names[i] = param.variable
+	}
+	rows, err := s.QueryContext(ctx, "select "+strings.Join(names, ", "))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	row := newFields(len(vars))
+	defer releaseFields(row)
+	if err = rows.Scan(row...); err != nil {
+		return err
+	}
+	for i, param := range vars {
+		if err = convertField(db, row[i].(*Field), names[i], outVal, param.fieldIdx); err != nil {
+			return err
+		}
+	}
+	return rows.Close()
+}
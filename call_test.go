@@ -0,0 +1,146 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestCallStatement(t *testing.T) {
+	params := []CallParam{{"?", false}, {"@sqlw_out2", true}}
+	if s := CallStatement("transfer", params); s != "call transfer(?, @sqlw_out2)" {
+		t.Fatalf("CallStatement: %q", s)
+	}
+	if s := ExecStatement("transfer", params); s != "exec transfer @p1, @p2 OUTPUT" {
+		t.Fatalf("ExecStatement: %q", s)
+	}
+	if s := ExecStatement("transfer", nil); s != "exec transfer" {
+		t.Fatalf("ExecStatement without params: %q", s)
+	}
+	if s := BlockStatement("transfer", params); s != "begin transfer(:1, :2); end;" {
+		t.Fatalf("BlockStatement: %q", s)
+	}
+}
+
+func Test_checkProcName(t *testing.T) {
+	for _, proc := range []string{"transfer", "bank.transfer", "`bank`.`trans fer`", `"bank"."transfer"`, "[dbo].[transfer]", "_p$1"} {
+		if err := checkProcName(proc); err != nil {
+			t.Fatalf("%q: %v", proc, err)
+		}
+	}
+	for _, proc := range []string{"", "1p", "p()", "p; drop table users", "bank.", ".p", "`p", "p--", "[]"} {
+		if err := checkProcName(proc); err == nil {
+			t.Fatalf("%q should be invalid", proc)
+		}
+	}
+}
+
+type callIn struct {
+	From   int64 `db:"from"`
+	To     int64 `db:"to"`
+	Amount int64 `db:"amount"`
+}
+
+type callOut struct {
+	Balance int64 `db:"balance"`
+	Retries int64 `db:"retries,inout"`
+	Status  int64 `db:"status,pos=1"`
+}
+
+func Test_callParams(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	params, err := callParams(db, &callIn{1, 2, 100}, &callOut{Retries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var values []interface{}
+	for _, param := range params {
+		names = append(names, param.name)
+		values = append(values, param.value)
+	}
+	if !reflect.DeepEqual(names, []string{"status", "from", "to", "amount", "balance", "retries"}) {
+		t.Fatalf("names: %v", names)
+	}
+	if !reflect.DeepEqual(values, []interface{}{nil, int64(1), int64(2), int64(100), nil, int64(3)}) {
+		t.Fatalf("values: %v", values)
+	}
+
+	type badPos struct {
+		A int64 `db:"a,pos=3"`
+	}
+	type samePos struct {
+		A int64 `db:"a,pos=1"`
+		B int64 `db:"b,pos=1"`
+	}
+	for _, out := range []interface{}{&badPos{}, &samePos{}, callOut{}} {
+		if _, err := callParams(db, nil, out); err == nil {
+			t.Fatalf("%T should fail", out)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	t.Run("mysql", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		server.respond("select @sqlw_out1, @sqlw_out5, @sqlw_out6", fakeResultSet{
+			[]string{"@sqlw_out1", "@sqlw_out5", "@sqlw_out6"},
+			[][]driver.Value{{int64(1), int64(900), int64(4)}},
+		})
+		out := callOut{Retries: 3}
+		result, err := db.Call("transfer", &callIn{1, 2, 100}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != (callOut{Balance: 900, Retries: 4, Status: 1}) {
+			t.Fatalf("out: %+v", out)
+		}
+		if result.Sql() != "[call transfer(@sqlw_out1, ?, ?, ?, @sqlw_out5, @sqlw_out6)], [1 2 100]" {
+			t.Fatalf("sql: %q", result.Sql())
+		}
+		if !reflect.DeepEqual(server.queries(), []string{
+			"set @sqlw_out6 = ?",
+			"call transfer(@sqlw_out1, ?, ?, ?, @sqlw_out5, @sqlw_out6)",
+			"select @sqlw_out1, @sqlw_out5, @sqlw_out6",
+		}) {
+			t.Fatalf("queries: %q", server.queries())
+		}
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		type Log struct {
+			Id int64 `db:"id"`
+		}
+		db, server := newFakeDB(t, "fakepostgres")
+		query := "call transfer(NULL, $1, $2, $3, NULL, $4)"
+		server.respond(query,
+			fakeResultSet{[]string{"status", "balance", "retries"}, [][]driver.Value{{int64(1), int64(900), int64(4)}}},
+			fakeResultSet{[]string{"id"}, [][]driver.Value{{int64(7)}}},
+		)
+		out := callOut{Retries: 3}
+		var logs []Log
+		if _, err := db.Call("transfer", &callIn{1, 2, 100}, &out, &logs); err != nil {
+			t.Fatal(err)
+		}
+		if out != (callOut{Balance: 900, Retries: 4, Status: 1}) || !reflect.DeepEqual(logs, []Log{{7}}) {
+			t.Fatalf("out: %+v, logs: %+v", out, logs)
+		}
+		if last := server.last(); last.query != query || !reflect.DeepEqual(last.args, []driver.Value{int64(1), int64(2), int64(100), int64(3)}) {
+			t.Fatalf("last: %+v", last)
+		}
+	})
+
+	t.Run("invalid proc", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		if _, err := db.Call("p; drop table users", nil, nil); err == nil {
+			t.Fatalf("invalid proc should fail")
+		}
+		if len(server.queries()) != 0 {
+			t.Fatalf("queries: %q", server.queries())
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CommentExtractor returns the sql comment tags carried by ctx, such as the route of the
+// request or the traceparent of the span.
+type CommentExtractor func(ctx context.Context) map[string]string
+
+type commentTagsKey struct{}
+
+// WithComment returns a copy of ctx carrying the sql comment tags, which override the tags
+// carried by ctx before.
+func WithComment(ctx context.Context, tags map[string]string) context.Context {
+	if prev, ok := ctx.Value(commentTagsKey{}).(map[string]string); ok {
+		merged := make(map[string]string, len(prev)+len(tags))
+		for k, v := range prev {
+			merged[k] = v
+		}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		tags = merged
+	}
+	return context.WithValue(ctx, commentTagsKey{}, tags)
+}
+
+// SetSqlComment appends the sqlcommenter comment, such as /*app='x',route='y'*/, to the
+// statements sent to the driver, the tags are merged from the static tags, WithComment
+// and the extractors, the later ones win. The statements already containing comments are not
+// changed. Prepare gets only the static tags, since the comment is baked into the Stmt and
+// shared by all its executions, so the executions of the Stmt don't carry the tags of their
+// ctx. Result, the loggers, the stats and the mapping cache still use the sql without the comment.
+func (db *DB) SetSqlComment(tags map[string]string, extractors ...CommentExtractor) {
+	static := make(map[string]string, len(tags))
+	for k, v := range tags {
+		static[k] = v
+	}
+	db.commentTags = static
+	db.commentExtractors = append([]CommentExtractor{}, extractors...)
+}
+
+// comment returns the sql comment for query, or "" if query already contains comments.
+func (db *DB) comment(ctx context.Context, query string) string {
+	ctxTags, _ := ctx.Value(commentTagsKey{}).(map[string]string)
+	if len(db.commentTags) == 0 && len(db.commentExtractors) == 0 && len(ctxTags) == 0 {
+		return ""
+	}
+	if db.hasComment(query) {
+		return ""
+	}
+	tags := make(map[string]string, len(db.commentTags)+len(ctxTags))
+	for k, v := range db.commentTags {
+		tags[k] = v
+	}
+	for k, v := range ctxTags {
+		tags[k] = v
+	}
+	for _, extractor := range db.commentExtractors {
+		for k, v := range extractor(ctx) {
+			tags[k] = v
+		}
+	}
+	return sqlComment(tags)
+}
+
+// staticComment returns the sql comment of the static tags for query, or "" if query already
+// contains comments.
+func (db *DB) staticComment(query string) string {
+	if len(db.commentTags) == 0 || db.hasComment(query) {
+		return ""
+	}
+	return sqlComment(db.commentTags)
+}
+
+// hasComment reports whether query contains comments outside the string literals and the
+// quoted identifiers.
+func (db *DB) hasComment(query string) bool {
+	for i := 0; i < len(query); {
+		end, comment := db.skipToken(query, i)
+		if comment {
+			return true
+		}
+		if end == i {
+			end++
+		}
+		i = end
+	}
+	return false
+}
+
+// appendComment appends comment to query, before the trailing semicolon if any.
+func appendComment(query, comment string) string {
+	if comment == "" {
+		return query
+	}
+	query = strings.TrimRight(query, " \t\r\n")
+	if strings.HasSuffix(query, ";") {
+		return strings.TrimSuffix(query, ";") + " " + comment + ";"
+	}
+	return query + " " + comment
+}
+
+// sqlComment formats the tags with the empty values skipped, in the sqlcommenter format:
+// sorted keys, url-encoded keys and values, and quoted values.
+func sqlComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if k != "" && v != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("/*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(commentEscape(k))
+		b.WriteString("='")
+		b.WriteString(commentEscape(tags[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
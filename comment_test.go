@@ -0,0 +1,86 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSqlComment(t *testing.T) {
+	comment := sqlComment(map[string]string{
+		"route":       "/users/{id}",
+		"app":         "it's app",
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"empty":       "",
+	})
+	want := `/*app='it%27s%20app',route='%2Fusers%2F%7Bid%7D',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/`
+	if comment != want {
+		t.Fatalf("sqlComment = %q, want %q", comment, want)
+	}
+	if got := appendComment("select 1; ", "/*a='b'*/"); got != "select 1 /*a='b'*/;" {
+		t.Fatalf("appendComment = %q", got)
+	}
+	if got := appendComment("select 1", ""); got != "select 1" {
+		t.Fatalf("appendComment = %q", got)
+	}
+}
+
+func Test_hasComment(t *testing.T) {
+	mysql := &DB{placeholder: "?", isMysql: true, dialect: dialectMysql}
+	pg := &DB{placeholder: "$", dialect: dialectPostgres}
+	tests := []struct {
+		db    *DB
+		query string
+		want  bool
+	}{
+		{mysql, "select 1 -- x", true},
+		{mysql, "select 1 /* x */", true},
+		{mysql, "select 1 # x", true},
+		{mysql, "select '--', \"/*\", `#` from t where a='it\\'s --'", false},
+		{pg, "select 1 # 2", false},
+		{pg, "select E'\\' -- x'", false},
+		{pg, "select E'\\\\' -- x", true},
+		{pg, "select '\\' -- x", true},
+		{pg, "select $$ -- $$, $f$ /* $f$", false},
+		{pg, "select a-1 from t", false},
+	}
+	for _, tt := range tests {
+		if got := tt.db.hasComment(tt.query); got != tt.want {
+			t.Fatalf("hasComment(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSetSqlComment(t *testing.T) {
+	db, server := newFakeDB(t, "fakemysql")
+	db.SetSqlComment(map[string]string{"app": "a"})
+	ctx := WithComment(context.Background(), map[string]string{"route": "r"})
+
+	if _, err := db.ExecContext(ctx, "delete from t where a='--'"); err != nil {
+		t.Fatal(err)
+	}
+	if q := server.last().query; q != "delete from t where a='--' /*app='a',route='r'*/" {
+		t.Fatalf("exec: %q", q)
+	}
+	if _, err := db.ExecContext(ctx, "delete from t -- x"); err != nil {
+		t.Fatal(err)
+	}
+	if q := server.last().query; q != "delete from t -- x" {
+		t.Fatalf("commented: %q", q)
+	}
+
+	stmt, err := db.PrepareContext(ctx, "delete from t where id=?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Stmt.Close()
+	if _, err = stmt.Exec(1); err != nil {
+		t.Fatal(err)
+	}
+	if q := server.last().query; q != "delete from t where id=? /*app='a'*/" {
+		t.Fatalf("prepare: %q", q)
+	}
+}
@@ -9,4 +9,25 @@ const (
 	opTypDelete = "delete"
 	opTypUpdate = "update"
 	opTypSelect = "select"
+	opTypCall   = "call"
+
+	opTypExec     = "exec"
+	opTypPrepare  = "prepare"
+	opTypBegin    = "begin"
+	opTypCommit   = "commit"
+	opTypRollback = "rollback"
+)
+
+// The op types of QueryInfo.
+const (
+	OpInsert   = opTypInsert
+	OpDelete   = opTypDelete
+	OpUpdate   = opTypUpdate
+	OpSelect   = opTypSelect
+	OpCall     = opTypCall
+	OpExec     = opTypExec
+	OpPrepare  = opTypPrepare
+	OpBegin    = opTypBegin
+	OpCommit   = opTypCommit
+	OpRollback = opTypRollback
 )
@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -35,29 +34,16 @@ func sqlMappingKey(opTyp, query string, typ reflect.Type) string {
 	return fmt.Sprintf("%v%v%v", opTyp, query, typ.String())
 }
 
-func queryRowContext(db *DB, ctx context.Context, selector Selector, parser FieldParser, dst interface{}, mapping *sync.Map, rawScan bool, query string, args ...interface{}) (Result, error) {
+func queryRowContext(db *DB, ctx context.Context, selector Selector, dst interface{}, query string, args ...interface{}) (Result, error) {
+	opts, args := parseQueryOptions(args)
 	if dst == nil {
 		return newResult(db, nil, query, args, false), fmt.Errorf("[sqlw %v] invalid dest value nil: %v", opTypSelect, reflect.TypeOf(dst))
 	}
 
-	rows, err := selector.QueryContext(ctx, query, args...)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return newResult(db, nil, query, args, true), nil
-		}
-		return newResult(db, nil, query, args, false), err
-	}
-	defer rows.Close()
-
-	notFound, err := rowsToStruct(rows, dst, parser, mapping, sqlMappingKey(opTypSelect, query, reflect.TypeOf(dst)), rawScan)
-	return newResult(db, nil, query, args, notFound), err
-}
-
-func queryContext(db *DB, ctx context.Context, selector Selector, parser FieldParser, dst interface{}, mapping *sync.Map, rawScan bool, query string, args ...interface{}) (Result, error) {
-	notFound := false
-	typ := reflect.TypeOf(dst)
-	if isStructPtr(typ) {
-		rows, err := selector.QueryContext(ctx, query, args...)
+	info := newQueryInfo(selector, nil, opTypSelect, query, args)
+	return db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		query, args := info.Query, info.Args
+		rows, err := selector.QueryContext(ctx, info.driverQuery, info.driverArgs...)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return newResult(db, nil, query, args, true), nil
@@ -65,32 +51,213 @@ func queryContext(db *DB, ctx context.Context, selector Selector, parser FieldPa
 			return newResult(db, nil, query, args, false), err
 		}
 		defer rows.Close()
-		notFound, err = rowsToStruct(rows, dst, parser, mapping, sqlMappingKey(opTypSelect, query, reflect.TypeOf(dst)), rawScan)
+
+		notFound, err := info.mapRows(dst, opts, func() (bool, error) {
+			return rowsToStruct(db, rows, dst, sqlMappingKey(opTypSelect, query, reflect.TypeOf(dst)), opts)
+		})
 		return newResult(db, nil, query, args, notFound), err
-	}
+	})
+}
 
-	if isStructSlicePtr(typ) {
-		rows, err := selector.QueryContext(ctx, query, args...)
-		if err != nil {
+func queryContext(db *DB, ctx context.Context, selector Selector, dst interface{}, query string, args ...interface{}) (Result, error) {
+	opts, args := parseQueryOptions(args)
+	info := newQueryInfo(selector, nil, opTypSelect, query, args)
+	return db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		query, args := info.Query, info.Args
+		typ := reflect.TypeOf(dst)
+		if isStructPtr(typ) && !isScalarType(db, typ.Elem()) || isStructSlicePtr(db, typ) || isScalarSlicePtr(db, typ) || isMapPtr(db, typ) || isKeyedMapPtr(db, typ) {
+			rows, err := selector.QueryContext(ctx, info.driverQuery, info.driverArgs...)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return newResult(db, nil, query, args, true), nil
+				}
+				return newResult(db, nil, query, args, false), err
+			}
+			defer rows.Close()
+			notFound, err := info.mapRows(dst, opts, func() (bool, error) {
+				return rowsToDst(db, rows, dst, sqlMappingKey(opTypSelect, query, typ), opts)
+			})
+			return newResult(db, nil, query, args, notFound), err
+		}
+
+		dstValue := reflect.Indirect(reflect.ValueOf(dst))
+		if db.needsField(dstValue.Type()) {
+			field := &Field{}
+			err := selector.QueryRowContext(ctx, info.driverQuery, info.driverArgs...).Scan(field)
 			if err == sql.ErrNoRows {
 				return newResult(db, nil, query, args, true), nil
 			}
+			if err == nil {
+				info.RowsReturned = 1
+				err = convertField(db, field, "", dstValue, -1)
+			}
 			return newResult(db, nil, query, args, false), err
 		}
-		defer rows.Close()
-		notFound, err = rowsToSlice(rows, dst, parser, mapping, sqlMappingKey(opTypSelect, query, reflect.TypeOf(dst)), rawScan)
-		return newResult(db, nil, query, args, notFound), err
+		err := selector.QueryRowContext(ctx, info.driverQuery, info.driverArgs...).Scan(dstValue.Addr().Interface())
+		if err == sql.ErrNoRows {
+			return newResult(db, nil, query, args, true), nil
+		}
+		if err == nil {
+			info.RowsReturned = 1
+		}
+		return newResult(db, nil, query, args, false), err
+	})
+}
+
+func rowsToDst(db *DB, rows *sql.Rows, dst interface{}, key string, opts *queryOptions) (bool, error) {
+	typ := reflect.TypeOf(dst)
+	switch {
+	case isStructPtr(typ) && !isScalarType(db, typ.Elem()):
+		if hasJoinFields(db, typ.Elem()) {
+			return rowsToJoined(db, rows, dst, key, opts)
+		}
+		return rowsToStruct(db, rows, dst, key, opts)
+	case typ != nil && typ.Kind() == reflect.Ptr && isScalarType(db, typ.Elem()):
+		return rowsToScalar(db, rows, dst)
+	case isScalarSlicePtr(db, typ):
+		return rowsToScalarSlice(db, rows, dst, opts)
+	case isMapPtr(db, typ):
+		return rowsToMap(db, rows, dst, opts)
+	case isKeyedMapPtr(db, typ):
+		return rowsToKeyedMap(db, rows, dst, key, opts)
+	case isStructSlicePtr(db, typ):
+		elemTyp := typ.Elem().Elem()
+		if elemTyp.Kind() == reflect.Ptr {
+			elemTyp = elemTyp.Elem()
+		}
+		if hasJoinFields(db, elemTyp) {
+			return rowsToJoined(db, rows, dst, key, opts)
+		}
+	default:
 	}
+	return rowsToSlice(db, rows, dst, key, opts)
+}
 
-	dstValue := reflect.Indirect(reflect.ValueOf(dst))
-	err := selector.QueryRowContext(ctx, query, args...).Scan(dstValue.Addr().Interface())
-	if err == sql.ErrNoRows {
-		return newResult(db, nil, query, args, true), nil
+// getFieldIdxMap returns the cached column name to struct field index mapping.
+func getFieldIdxMap(db *DB, columns []string, elemTyp reflect.Type, key string) map[string]int {
+	var fieldIdxMap map[string]int
+	var stored, ok = db.mapping.Load(key)
+	if ok {
+		fieldIdxMap = stored.(map[string]int)
+	} else {
+		fieldIdxMap = map[string]int{}
+		existsMap := map[string]string{}
+		for _, column := range columns {
+			if _, ok := existsMap[db.columnKey(column)]; !ok {
+				existsMap[db.columnKey(column)] = column
+			}
+		}
+		for j := 0; j < elemTyp.NumField(); j++ {
+			strField := elemTyp.Field(j)
+			fieldName := db.parseFieldName(&strField)
+			if column, ok := existsMap[db.columnKey(fieldName)]; ok && fieldName != "" {
+				fieldIdxMap[column] = j
+			}
+		}
+		db.mapping.Store(key, fieldIdxMap)
+	}
+	return fieldIdxMap
+}
+
+type columnCheckKey struct {
+	key string
+}
+
+// columnMismatch is cached per mapping key since the columns of a mapping key are fixed.
+type columnMismatch struct {
+	unmapped []string
+	missing  []string
+}
+
+// checkColumns returns *ColumnMappingError for the mismatches enabled by the column check,
+// the mismatches are computed by f once per mapping key.
+func checkColumns(db *DB, opts *queryOptions, key string, elemTyp reflect.Type, f func() *columnMismatch) error {
+	check := opts.getColumnCheck(db)
+	if check == 0 {
+		return nil
+	}
+	var m *columnMismatch
+	if stored, ok := db.mapping.Load(columnCheckKey{key}); ok {
+		m = stored.(*columnMismatch)
+	} else {
+		m = f()
+		db.mapping.Store(columnCheckKey{key}, m)
+	}
+	var unmapped, missing []string
+	if check&CheckUnmappedColumns != 0 {
+		unmapped = m.unmapped
+	}
+	if check&CheckMissingFields != 0 {
+		missing = m.missing
+	}
+	if len(unmapped) == 0 && len(missing) == 0 {
+		return nil
+	}
+	return &ColumnMappingError{Type: elemTyp.String(), UnmappedColumns: unmapped, MissingFields: missing}
+}
+
+// structMismatch returns the columns not mapped by fieldIdxMap and the fields of elemTyp
+// without columns.
+func structMismatch(db *DB, columns []string, elemTyp reflect.Type, fieldIdxMap map[string]int) *columnMismatch {
+	m := &columnMismatch{}
+	mapped := map[int]bool{}
+	for _, column := range columns {
+		if fieldIdx, ok := fieldIdxMap[column]; ok {
+			mapped[fieldIdx] = true
+		} else {
+			m.unmapped = append(m.unmapped, column)
+		}
+	}
+	m.missing = missingFields(db, elemTyp, mapped, "")
+	return m
+}
+
+// missingFields returns the named fields of typ not in mapped, as "Name(column)".
+func missingFields(db *DB, typ reflect.Type, mapped map[int]bool, prefix string) []string {
+	var missing []string
+	for j := 0; j < typ.NumField(); j++ {
+		strField := typ.Field(j)
+		fieldName := db.parseFieldName(&strField)
+		if fieldName == "" || mapped[j] {
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%v%v(%v)", prefix, strField.Name, fieldName))
 	}
-	return newResult(db, nil, query, args, false), err
+	return missing
 }
 
-func rowsToStruct(rows *sql.Rows, dst interface{}, parser FieldParser, mapping *sync.Map, key string, rawScan bool) (bool, error) {
+// scanStruct scans the current row into dstValue, row should be made by make([]interface{}, n)
+// when rawScan is true, or by newFields(n) otherwise.
+// The fields with conversion tag options are always converted by Field.
+func scanStruct(db *DB, rows *sql.Rows, row []interface{}, columns []string, fieldIdxMap map[string]int, dstValue reflect.Value) error {
+	var fieldsOpts []*fieldConvertOptions
+	if db.rawScan {
+		fieldsOpts = getFieldConvertOptions(db, dstValue.Type())
+		for i, fieldName := range columns {
+			if fieldIdx, ok := fieldIdxMap[fieldName]; ok && fieldsOpts[fieldIdx] == nil {
+				row[i] = dstValue.Field(fieldIdx).Addr().Interface()
+			} else {
+				row[i] = &Field{}
+			}
+		}
+	}
+
+	if err := rows.Scan(row...); err != nil {
+		return err
+	}
+
+	for i, fieldName := range columns {
+		if fieldIdx, ok := fieldIdxMap[fieldName]; ok && (!db.rawScan || fieldsOpts[fieldIdx] != nil) {
+			field := row[i].(*Field)
+			if err := convertField(db, field, columns[i], dstValue, fieldIdx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func rowsToStruct(db *DB, rows *sql.Rows, dst interface{}, key string, opts *queryOptions) (bool, error) {
 	dstTyp := reflect.TypeOf(dst)
 	// if !isStructPtr(dstTyp) {
 	// 	return fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
@@ -105,64 +272,31 @@ func rowsToStruct(rows *sql.Rows, dst interface{}, parser FieldParser, mapping *
 	// 	columns[i] = strings.ToLower(v)
 	// }
 
-	var elemTyp = dstTyp.Elem()
-	var fieldIdxMap map[string]int
-	var stored, ok = mapping.Load(key)
-	if ok {
-		fieldIdxMap = stored.(map[string]int)
-	} else {
-		fieldIdxMap = map[string]int{}
-		existsMap := map[string]bool{}
-		for _, fieldName := range columns {
-			existsMap[fieldName] = true
-		}
-		for j := 0; j < elemTyp.NumField(); j++ {
-			strField := elemTyp.Field(j)
-			fieldName := parser(&strField)
-			if existsMap[fieldName] {
-				fieldIdxMap[fieldName] = j
-			}
-		}
-		mapping.Store(key, fieldIdxMap)
+	fieldIdxMap := getFieldIdxMap(db, columns, dstTyp.Elem(), key)
+	if err = checkColumns(db, opts, key, dstTyp.Elem(), func() *columnMismatch {
+		return structMismatch(db, columns, dstTyp.Elem(), fieldIdxMap)
+	}); err != nil {
+		return false, err
 	}
 	if rows.Next() {
-		if rawScan {
-			row := make([]interface{}, len(columns))
-			dstValue := reflect.Indirect(reflect.ValueOf(dst))
-			for i, fieldName := range columns {
-				if fieldIdx, ok := fieldIdxMap[fieldName]; ok {
-					row[i] = dstValue.Field(fieldIdx).Addr().Interface()
-				} else {
-					row[i] = &Field{}
-				}
-			}
-			if err = rows.Scan(row...); err != nil {
-				return false, err
-			}
+		var row []interface{}
+		if db.rawScan {
+			row = make([]interface{}, len(columns))
 		} else {
-			row := newFields(len(columns))
+			row = newFields(len(columns))
 			defer releaseFields(row)
-
-			if err = rows.Scan(row...); err != nil {
-				return false, err
-			}
-
-			dstValue := reflect.Indirect(reflect.ValueOf(dst))
-			for i, fieldName := range columns {
-				if fieldIdx, ok := fieldIdxMap[fieldName]; ok {
-					field := row[i].(*Field)
-					field.ToValue(dstValue.Field(fieldIdx))
-				}
-			}
+		}
+		if err = scanStruct(db, rows, row, columns, fieldIdxMap, reflect.Indirect(reflect.ValueOf(dst))); err != nil {
+			return false, err
 		}
 		return false, nil
 	}
 	return true, nil
 }
 
-func rowsToSlice(rows *sql.Rows, dst interface{}, parser FieldParser, mapping *sync.Map, key string, rawScan bool) (bool, error) {
+func rowsToSlice(db *DB, rows *sql.Rows, dst interface{}, key string, opts *queryOptions) (bool, error) {
 	dstTyp := reflect.TypeOf(dst)
-	if !isStructSlicePtr(dstTyp) {
+	if !isStructSlicePtr(db, dstTyp) {
 		return false, fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
 	}
 
@@ -180,80 +314,405 @@ func rowsToSlice(rows *sql.Rows, dst interface{}, parser FieldParser, mapping *s
 	if isPtrType {
 		elemTyp = elemTyp.Elem()
 	}
-	var fieldIdxMap map[string]int
-	var stored, ok = mapping.Load(key)
-	if ok {
-		fieldIdxMap = stored.(map[string]int)
-	} else {
-		fieldIdxMap = map[string]int{}
-		existsMap := map[string]bool{}
-		for _, fieldName := range columns {
-			existsMap[fieldName] = true
-		}
-		for j := 0; j < elemTyp.NumField(); j++ {
-			strField := elemTyp.Field(j)
-			fieldName := parser(&strField)
-			if existsMap[fieldName] {
-				fieldIdxMap[fieldName] = j
-			}
-		}
-		mapping.Store(key, fieldIdxMap)
+	fieldIdxMap := getFieldIdxMap(db, columns, elemTyp, key)
+	if err = checkColumns(db, opts, key, elemTyp, func() *columnMismatch {
+		return structMismatch(db, columns, elemTyp, fieldIdxMap)
+	}); err != nil {
+		return false, err
 	}
 
 	dstValue := reflect.Indirect(reflect.ValueOf(dst))
 	var row []interface{}
-	if rawScan {
+	if db.rawScan {
 		row = make([]interface{}, len(columns))
 	} else {
 		row = newFields(len(columns))
 		defer releaseFields(row)
 	}
 
-	if dstValue.Len() > 0 {
+	prepareSlice(dstValue, opts)
+
+	notFound := true
+	for rows.Next() {
+		notFound = false
+		var dstElemVal reflect.Value
+		if isPtrType && opts.reuse {
+			dstElemVal = reusableElem(dstValue)
+		}
+		if !dstElemVal.IsValid() {
+			dstElemVal = reflect.Indirect(reflect.New(elemTyp))
+		}
+		if err = scanStruct(db, rows, row, columns, fieldIdxMap, dstElemVal); err != nil {
+			return notFound, err
+		}
+
+		if isPtrType {
+			dstValue.Set(reflect.Append(dstValue, dstElemVal.Addr()))
+		} else {
+			dstValue.Set(reflect.Append(dstValue, dstElemVal))
+		}
+	}
+
+	// if notFound {
+	// 	return ErrNotFound
+	// }
+
+	return notFound, err
+}
+
+// prepareSlice truncates the slice unless appending, and grows it by the capacity hint.
+func prepareSlice(dstValue reflect.Value, opts *queryOptions) {
+	if !opts.appendRows && dstValue.Len() > 0 {
 		dstValue.Set(dstValue.Slice(0, 0))
 	}
+	if n := opts.capacity; n > 0 && dstValue.Cap()-dstValue.Len() < n {
+		newValue := reflect.MakeSlice(dstValue.Type(), dstValue.Len(), dstValue.Len()+n)
+		reflect.Copy(newValue, dstValue)
+		dstValue.Set(newValue)
+	}
+}
+
+// reusableElem returns the zeroed element pointed to by the backing array of the []*T slice
+// right after its length, or an invalid value if there's none.
+func reusableElem(dstValue reflect.Value) reflect.Value {
+	n := dstValue.Len()
+	if n >= dstValue.Cap() {
+		return reflect.Value{}
+	}
+	ptr := dstValue.Slice(0, n+1).Index(n)
+	if ptr.IsNil() {
+		return reflect.Value{}
+	}
+	elem := ptr.Elem()
+	elem.Set(reflect.Zero(elem.Type()))
+	return elem
+}
+
+func rowsToScalarSlice(db *DB, rows *sql.Rows, dst interface{}, opts *queryOptions) (bool, error) {
+	dstTyp := reflect.TypeOf(dst)
+	if !isScalarSlicePtr(db, dstTyp) {
+		return false, fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	if len(columns) != 1 {
+		return false, fmt.Errorf("[sqlw %v] invalid columns num %v for dest type %v, should be 1", opTypSelect, len(columns), dstTyp)
+	}
+
+	elemTyp := dstTyp.Elem().Elem()
+	rawScan := db.rawScan && !db.needsField(elemTyp)
+	dstValue := reflect.Indirect(reflect.ValueOf(dst))
+	prepareSlice(dstValue, opts)
+
+	var row []interface{}
+	if !rawScan {
+		row = newFields(1)
+		defer releaseFields(row)
+	}
 
 	notFound := true
 	for rows.Next() {
 		notFound = false
-		dstElemVal := reflect.Indirect(reflect.New(elemTyp))
+		dstElemVal := reflect.New(elemTyp)
 		if rawScan {
-			for i, fieldName := range columns {
-				if fieldIdx, ok := fieldIdxMap[fieldName]; ok {
-					row[i] = dstElemVal.Field(fieldIdx).Addr().Interface()
-				} else {
-					row[i] = &Field{}
-				}
+			err = rows.Scan(dstElemVal.Interface())
+		} else {
+			err = rows.Scan(row...)
+		}
+		if err != nil {
+			return notFound, err
+		}
+		if !rawScan {
+			if err = convertField(db, row[0].(*Field), columns[0], dstElemVal.Elem(), -1); err != nil {
+				return notFound, err
 			}
 		}
+		dstValue.Set(reflect.Append(dstValue, dstElemVal.Elem()))
+	}
+
+	return notFound, rows.Err()
+}
+
+func rowsToMap(db *DB, rows *sql.Rows, dst interface{}, opts *queryOptions) (bool, error) {
+	dstTyp := reflect.TypeOf(dst)
+	if !isMapPtr(db, dstTyp) {
+		return false, fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	if len(columns) != 2 {
+		return false, fmt.Errorf("[sqlw %v] invalid columns num %v for dest type %v, should be 2", opTypSelect, len(columns), dstTyp)
+	}
 
+	mapTyp := dstTyp.Elem()
+	keyTyp, elemTyp := mapTyp.Key(), mapTyp.Elem()
+	rawScan := db.rawScan && !db.needsField(keyTyp) && !db.needsField(elemTyp)
+	dstValue, err := prepareMap(reflect.Indirect(reflect.ValueOf(dst)), opts)
+	if err != nil {
+		return false, err
+	}
+
+	var row []interface{}
+	if rawScan {
+		row = make([]interface{}, 2)
+	} else {
+		row = newFields(2)
+		defer releaseFields(row)
+	}
+
+	notFound := true
+	for rows.Next() {
+		notFound = false
+		keyVal := reflect.New(keyTyp)
+		elemVal := reflect.New(elemTyp)
+		if rawScan {
+			row[0], row[1] = keyVal.Interface(), elemVal.Interface()
+		}
 		if err = rows.Scan(row...); err != nil {
 			return notFound, err
 		}
-
 		if !rawScan {
-			for i, fieldName := range columns {
-				if fieldIdx, ok := fieldIdxMap[fieldName]; ok {
-					field, ok := row[i].(*Field)
-					if ok {
-						field.ToValue(dstElemVal.Field(fieldIdx))
-					}
-				}
+			if err = convertField(db, row[0].(*Field), columns[0], keyVal.Elem(), -1); err != nil {
+				return notFound, err
+			}
+			if err = convertField(db, row[1].(*Field), columns[1], elemVal.Elem(), -1); err != nil {
+				return notFound, err
 			}
 		}
+		dstValue.SetMapIndex(keyVal.Elem(), elemVal.Elem())
+	}
 
-		if isPtrType {
-			dstValue.Set(reflect.Append(dstValue, dstElemVal.Addr()))
+	return notFound, rows.Err()
+}
+
+// rowsToKeyedMap fills *map[K]T, *map[K]*T, *map[K][]T or *map[K][]*T, the key is taken from
+// the column specified by KeyBy or by the "key" tag option.
+func rowsToKeyedMap(db *DB, rows *sql.Rows, dst interface{}, key string, opts *queryOptions) (bool, error) {
+	dstTyp := reflect.TypeOf(dst)
+	if !isKeyedMapPtr(db, dstTyp) {
+		return false, fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+
+	mapTyp := dstTyp.Elem()
+	keyTyp, valTyp := mapTyp.Key(), mapTyp.Elem()
+	isSliceVal := valTyp.Kind() == reflect.Slice
+	elemTyp := valTyp
+	if isSliceVal {
+		elemTyp = elemTyp.Elem()
+	}
+	isPtrType := elemTyp.Kind() == reflect.Ptr
+	if isPtrType {
+		elemTyp = elemTyp.Elem()
+	}
+
+	keyColumn := opts.keyBy
+	if keyColumn == "" {
+		for i := 0; i < elemTyp.NumField(); i++ {
+			strField := elemTyp.Field(i)
+			if db.parseFieldOptions(&strField).Contains("key") {
+				keyColumn = db.parseFieldName(&strField)
+				break
+			}
+		}
+	}
+	if keyColumn == "" {
+		return false, fmt.Errorf("[sqlw %v] no key column for dest type %v, use sqlw.KeyBy or the \"key\" tag option", opTypSelect, dstTyp)
+	}
+	keyColumnIdx := -1
+	for i, column := range columns {
+		if db.columnKey(column) == db.columnKey(keyColumn) {
+			keyColumnIdx = i
+			break
+		}
+	}
+	if keyColumnIdx < 0 {
+		return false, fmt.Errorf("[sqlw %v] key column %v not found in columns: %v", opTypSelect, keyColumn, columns)
+	}
+	keyColumn = columns[keyColumnIdx]
+
+	fieldIdxMap := getFieldIdxMap(db, columns, elemTyp, key)
+	if err = checkColumns(db, opts, key, elemTyp, func() *columnMismatch {
+		return structMismatch(db, columns, elemTyp, fieldIdxMap)
+	}); err != nil {
+		return false, err
+	}
+	keyFieldIdx, keyMapped := fieldIdxMap[keyColumn]
+	if keyMapped && !elemTyp.Field(keyFieldIdx).Type.ConvertibleTo(keyTyp) {
+		return false, fmt.Errorf("[sqlw %v] key column %v of type %v is not convertible to %v", opTypSelect, keyColumn, elemTyp.Field(keyFieldIdx).Type, keyTyp)
+	}
+
+	dstValue, err := prepareMap(reflect.Indirect(reflect.ValueOf(dst)), opts)
+	if err != nil {
+		return false, err
+	}
+	var row []interface{}
+	if db.rawScan {
+		row = make([]interface{}, len(columns))
+	} else {
+		row = newFields(len(columns))
+		defer releaseFields(row)
+	}
+
+	notFound := true
+	for rows.Next() {
+		notFound = false
+		dstElemVal := reflect.Indirect(reflect.New(elemTyp))
+		if err = scanStruct(db, rows, row, columns, fieldIdxMap, dstElemVal); err != nil {
+			return notFound, err
+		}
+
+		var keyVal reflect.Value
+		if keyMapped {
+			keyVal = dstElemVal.Field(keyFieldIdx).Convert(keyTyp)
 		} else {
-			dstValue.Set(reflect.Append(dstValue, dstElemVal))
+			keyVal = reflect.New(keyTyp).Elem()
+			if err = convertField(db, row[keyColumnIdx].(*Field), keyColumn, keyVal, -1); err != nil {
+				return notFound, err
+			}
 		}
+
+		elemVal := dstElemVal
+		if isPtrType {
+			elemVal = dstElemVal.Addr()
+		}
+		if isSliceVal {
+			sliceVal := dstValue.MapIndex(keyVal)
+			if !sliceVal.IsValid() {
+				sliceVal = reflect.Zero(valTyp)
+			}
+			elemVal = reflect.Append(sliceVal, elemVal)
+		}
+		dstValue.SetMapIndex(keyVal, elemVal)
 	}
 
-	// if notFound {
-	// 	return ErrNotFound
-	// }
+	return notFound, rows.Err()
+}
 
-	return notFound, err
+// convertField sets the column value to dstVal, which is the fieldIdx field of dstVal
+// if fieldIdx >= 0, and wraps the error with the column and the destination.
+func convertField(db *DB, field *Field, column string, dstVal reflect.Value, fieldIdx int) error {
+	opts := &db.convertOptions
+	structVal := dstVal
+	if fieldIdx >= 0 {
+		dstVal = structVal.Field(fieldIdx)
+		if fieldOpts := getFieldConvertOptions(db, structVal.Type())[fieldIdx]; fieldOpts != nil {
+			merged := *opts
+			fieldOpts.mergeTo(&merged)
+			opts = &merged
+		}
+	}
+	if err := field.toValue(dstVal, opts); err != nil {
+		dst := dstVal.Type().String()
+		if fieldIdx >= 0 {
+			dst = structVal.Type().String() + "." + structVal.Type().Field(fieldIdx).Name
+		}
+		value := field.value()
+		if b, ok := value.([]byte); ok {
+			value = append([]byte{}, b...)
+		}
+		return &ConvertError{Column: column, Field: dst, Value: value, Err: err}
+	}
+	return nil
+}
+
+type fieldConvertOptionsKey struct {
+	typ reflect.Type
+}
+
+// fieldConvertOptions are the conversion options set by tag options, such as
+// `db:"created,layout=2006-01-02,epoch=ms"`, the fields having them and the fields
+// of types not supported by the drivers are always converted by Field.
+type fieldConvertOptions struct {
+	layout     string
+	epoch      EpochUnit
+	hasEpoch   bool
+	serializer Serializer
+}
+
+func (o *fieldConvertOptions) mergeTo(opts *convertOptions) {
+	if o.layout != "" {
+		opts.layouts = []string{o.layout}
+	}
+	if o.hasEpoch {
+		opts.epoch = o.epoch
+	}
+	opts.serializer = o.serializer
+}
+
+// getFieldConvertOptions returns the cached conversion options of the struct fields,
+// nil for the fields that can be scanned by the driver directly.
+func getFieldConvertOptions(db *DB, typ reflect.Type) []*fieldConvertOptions {
+	key := fieldConvertOptionsKey{typ}
+	if stored, ok := db.mapping.Load(key); ok {
+		return stored.([]*fieldConvertOptions)
+	}
+	fieldsOpts := make([]*fieldConvertOptions, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		strField := typ.Field(i)
+		tagOpts := db.parseFieldOptions(&strField)
+		viaField := db.needsField(strField.Type)
+		if tagOpts == "" && !viaField {
+			continue
+		}
+		fieldOpts := &fieldConvertOptions{}
+		fieldOpts.layout, _ = tagOpts.Get("layout")
+		if epoch, ok := tagOpts.Get("epoch"); ok {
+			fieldOpts.epoch, fieldOpts.hasEpoch = parseEpochUnit(epoch)
+		}
+		for _, opt := range strings.Split(string(tagOpts), ",") {
+			if s, ok := db.serializers[strings.TrimSpace(opt)]; ok {
+				fieldOpts.serializer = s
+				break
+			}
+		}
+		if viaField || fieldOpts.layout != "" || fieldOpts.hasEpoch || fieldOpts.serializer != nil {
+			fieldsOpts[i] = fieldOpts
+		}
+	}
+	db.mapping.Store(key, fieldsOpts)
+	return fieldsOpts
+}
+
+// prepareMap clears the map unless appending, the capacity hint sizes the new maps, and
+// Reuse is not supported since the map values are not addressable.
+func prepareMap(mapVal reflect.Value, opts *queryOptions) (reflect.Value, error) {
+	if opts.reuse {
+		return mapVal, fmt.Errorf("[sqlw %v] option Reuse is not supported by dest type %v", opTypSelect, mapVal.Type())
+	}
+	if mapVal.IsNil() && opts.capacity > 0 {
+		mapVal.Set(reflect.MakeMapWithSize(mapVal.Type(), opts.capacity))
+		return mapVal, nil
+	}
+	if opts.appendRows {
+		if mapVal.IsNil() {
+			mapVal.Set(reflect.MakeMap(mapVal.Type()))
+		}
+		return mapVal, nil
+	}
+	return resetMap(mapVal), nil
+}
+
+// resetMap makes a new map for nil mapVal or deletes all the existing keys.
+func resetMap(mapVal reflect.Value) reflect.Value {
+	if mapVal.IsNil() {
+		mapVal.Set(reflect.MakeMap(mapVal.Type()))
+	} else {
+		for _, k := range mapVal.MapKeys() {
+			mapVal.SetMapIndex(k, reflect.Value{})
+		}
+	}
+	return mapVal
 }
 
 var empty = struct{}{}
@@ -404,6 +863,10 @@ func insertContext(ctx context.Context, selector Selector, stmt *Stmt, sqlHead s
 	}
 
 	if raw {
+		args, err := db.encodeArgs(args)
+		if err != nil {
+			return newResult(db, nil, sqlHead, args, false), err
+		}
 		if !isStmt {
 			if needVal && len(args) > 0 {
 				fieldNames, _, err := parseInsertFields(sqlHead, sqlHeadLower)
@@ -429,18 +892,17 @@ func insertContext(ctx context.Context, selector Selector, stmt *Stmt, sqlHead s
 				}
 			}
 
-			result, err := selector.ExecContext(ctx, sqlHead+sqlTail, args...)
-			return newResult(db, result, sqlHead, args, false), err
+			return execContext(db, ctx, selector, nil, opTypInsert, sqlHead+sqlTail, args, nil)
 		}
 
-		result, err := stmt.ExecContext(ctx, args...)
-		return newResult(db, result, stmt.query, args, false), err
+		return execContext(db, ctx, nil, stmt, opTypInsert, stmt.query, args, nil)
 	}
 
 	var err error
 	var fieldValues []interface{}
 	var insertItems []reflect.Value
 	var dataVal = reflect.ValueOf(data)
+	var cols = &argColumns{}
 
 	info, err := getInsertModelInfo(sqlHead, sqlHeadLower, dataTyp, db, db.parseFieldName)
 	if err != nil {
@@ -479,7 +941,12 @@ INIT_FIELD_VALUES:
 				sqlTail += "("
 				for j, fieldName := range info.FieldNames {
 					if idx, ok := info.FieldIndexes[fieldName]; ok {
-						fieldValues = append(fieldValues, item.Field(idx).Interface())
+						v, err := db.encodeField(item, idx)
+						if err != nil {
+							return newResult(db, nil, info.SqlHead, args, false), err
+						}
+						fieldValues = append(fieldValues, v)
+						cols.add(fieldName, getSecretFields(db, item.Type())[idx])
 						valueIdx++
 						sqlTail += db.placeholderBuilder(valueIdx)
 						if j != len(info.FieldNames)-1 {
@@ -496,27 +963,34 @@ INIT_FIELD_VALUES:
 			for _, item := range insertItems {
 				for _, fieldName := range info.FieldNames {
 					if idx, ok := info.FieldIndexes[fieldName]; ok {
-						fieldValues = append(fieldValues, item.Field(idx).Interface())
+						v, err := db.encodeField(item, idx)
+						if err != nil {
+							return newResult(db, nil, info.SqlHead, args, false), err
+						}
+						fieldValues = append(fieldValues, v)
+						cols.add(fieldName, getSecretFields(db, item.Type())[idx])
 					}
 				}
 			}
 		}
 
-		query := info.SqlHead + sqlTail
-		result, err := selector.ExecContext(ctx, query, fieldValues...)
-		return newResult(db, result, query, fieldValues, false), err
+		return execContext(db, ctx, selector, nil, opTypInsert, info.SqlHead+sqlTail, fieldValues, cols)
 	}
 
 	for _, item := range insertItems {
 		for _, fieldName := range info.FieldNames {
 			if idx, ok := info.FieldIndexes[fieldName]; ok {
-				fieldValues = append(fieldValues, item.Field(idx).Interface())
+				v, err := db.encodeField(item, idx)
+				if err != nil {
+					return newResult(db, nil, stmt.query, args, false), err
+				}
+				fieldValues = append(fieldValues, v)
+				cols.add(fieldName, getSecretFields(db, item.Type())[idx])
 			}
 		}
 	}
 
-	result, err := stmt.ExecContext(ctx, fieldValues...)
-	return newResult(db, result, stmt.query, fieldValues, false), err
+	return execContext(db, ctx, nil, stmt, opTypInsert, stmt.query, fieldValues, cols)
 }
 
 func getUpdateModelInfo(sqlHead string, dataTyp reflect.Type, db *DB) (*MappingInfo, error) {
@@ -652,25 +1126,32 @@ func updateContext(ctx context.Context, selector Selector, db *DB, stmt *Stmt, s
 		dataVal = dataVal.Elem()
 	}
 
+	cols := &argColumns{}
 	fieldValues = make([]interface{}, len(info.FieldNames)+len(args))[0:0]
 	for _, fieldName := range info.FieldNames {
 		if idx, ok := info.FieldIndexes[fieldName]; ok {
-			fieldValues = append(fieldValues, dataVal.Field(idx).Interface())
+			v, err := db.encodeField(dataVal, idx)
+			if err != nil {
+				return newResult(db, nil, info.SqlHead, args, false), err
+			}
+			fieldValues = append(fieldValues, v)
+			cols.add(fieldName, getSecretFields(db, dataVal.Type())[idx])
 		}
 	}
 
 	if len(args) > 0 {
+		args, err = db.encodeArgs(args)
+		if err != nil {
+			return newResult(db, nil, info.SqlHead, args, false), err
+		}
 		fieldValues = append(fieldValues, args...)
 	}
 
 	if !isStmt {
-		query := info.SqlHead
-		result, err := selector.ExecContext(ctx, query, fieldValues...)
-		return newResult(db, result, query, fieldValues, false), err
+		return execContext(db, ctx, selector, nil, opTypUpdate, info.SqlHead, fieldValues, cols)
 	}
 
-	result, err := stmt.ExecContext(ctx, fieldValues...)
-	return newResult(db, result, stmt.query, fieldValues, false), err
+	return execContext(db, ctx, nil, stmt, opTypUpdate, stmt.query, fieldValues, cols)
 }
 
 func updateByExecContext(ctx context.Context, selector Selector, db *DB, stmt *Stmt, query string, args ...interface{}) (Result, error) {
@@ -691,13 +1172,7 @@ func updateByExecContext(ctx context.Context, selector Selector, db *DB, stmt *S
 	}
 
 	if obj == nil {
-		if selector != nil {
-			result, err := selector.ExecContext(ctx, query, args...)
-			return newResult(db, result, query, args, false), err
-		}
-
-		result, err := stmt.ExecContext(ctx, args...)
-		return newResult(db, result, query, args, false), err
+		return execContext(db, ctx, selector, stmt, opTypUpdate, query, args, nil)
 	}
 
 	return updateContext(ctx, selector, db, stmt, query, obj, args...)
@@ -711,10 +1186,13 @@ func isStructPtr(t reflect.Type) bool {
 	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
 }
 
-func isStructSlicePtr(t reflect.Type) bool {
+func isStructSlicePtr(db *DB, t reflect.Type) bool {
 	elem := t.Elem()
 	if t.Kind() == reflect.Ptr && elem.Kind() == reflect.Slice {
 		sliceElem := elem.Elem()
+		if isScalarType(db, sliceElem) {
+			return false
+		}
 		if sliceElem.Kind() == reflect.Struct || isStructPtr(sliceElem) {
 			return true
 		}
@@ -722,6 +1200,71 @@ func isStructSlicePtr(t reflect.Type) bool {
 	return false
 }
 
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// isScalarType reports whether t is filled from a single column,
+// such as numbers, strings, []byte, time.Time and sql.Scanner types.
+func isScalarType(db *DB, t reflect.Type) bool {
+	if t == timeType || isBigType(t) || t.Implements(scannerType) || reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	if db != nil && db.getConverter(t) != nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Ptr:
+		return isScalarType(db, t.Elem())
+	default:
+	}
+	return false
+}
+
+func isScalarSlicePtr(db *DB, t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Slice {
+		return false
+	}
+	// *[]byte is a single column value
+	sliceElem := t.Elem().Elem()
+	return sliceElem.Kind() != reflect.Uint8 && isScalarType(db, sliceElem)
+}
+
+// isKeyedMapPtr reports whether t is *map[K]T, *map[K]*T, *map[K][]T or *map[K][]*T with struct T.
+func isKeyedMapPtr(db *DB, t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Map {
+		return false
+	}
+	mapTyp := t.Elem()
+	if !isScalarType(db, mapTyp.Key()) {
+		return false
+	}
+	elemTyp := mapTyp.Elem()
+	if elemTyp.Kind() == reflect.Slice {
+		elemTyp = elemTyp.Elem()
+	}
+	if elemTyp.Kind() == reflect.Ptr {
+		elemTyp = elemTyp.Elem()
+	}
+	return elemTyp.Kind() == reflect.Struct && !isScalarType(db, elemTyp)
+}
+
+func isMapPtr(db *DB, t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Map {
+		return false
+	}
+	mapTyp := t.Elem()
+	return isScalarType(db, mapTyp.Key()) && isScalarType(db, mapTyp.Elem())
+}
+
 func isInsertable(t reflect.Type) bool {
 	kind := t.Kind()
 	if kind == reflect.Struct || (kind == reflect.Ptr && t.Elem().Kind() == reflect.Struct) {
@@ -735,5 +1278,5 @@ func isInsertable(t reflect.Type) bool {
 		}
 	}
 
-	return isStructSlicePtr(t)
+	return isStructSlicePtr(nil, t)
 }
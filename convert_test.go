@@ -5,9 +5,12 @@
 package sqlw
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Benchmark_sqlMappingKey2(b *testing.B) {
@@ -60,3 +63,260 @@ func Benchmark_sqlMappingKey(b *testing.B) {
 		}
 	}
 }
+
+func TestIsScalarType(t *testing.T) {
+	var i int
+	tests := map[reflect.Type]bool{
+		reflect.TypeOf(i):                      true,
+		reflect.TypeOf(&i):                     true,
+		reflect.TypeOf(""):                     true,
+		reflect.TypeOf([]byte{}):               true,
+		reflect.TypeOf(time.Time{}):            true,
+		reflect.TypeOf(sql.NullString{}):       true,
+		reflect.TypeOf([]interface{}{}).Elem(): false,
+		reflect.TypeOf([4]int{}):               false,
+		reflect.TypeOf([]int{}):                false,
+		reflect.TypeOf(struct{}{}):             false,
+	}
+	for typ, want := range tests {
+		if got := isScalarType(nil, typ); got != want {
+			t.Fatalf("isScalarType(%v) = %v, want %v", typ, got, want)
+		}
+	}
+	if isScalarSlicePtr(nil, reflect.TypeOf(&[]interface{}{})) || isMapPtr(nil, reflect.TypeOf(&map[string]interface{}{})) {
+		t.Fatalf("interface elements should not be scalars")
+	}
+}
+
+func TestRowsToScalarSlice(t *testing.T) {
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select id from t", fakeResultSet{[]string{"id"}, [][]driver.Value{{int64(1)}, {int64(2)}, {[]byte("3")}}})
+	server.respond("select id, name from t", fakeResultSet{[]string{"id", "name"}, [][]driver.Value{{int64(1), "a"}}})
+	for _, rawScan := range []bool{true, false} {
+		db.SetRawScan(rawScan)
+		var ids []int64
+		if _, err := db.Select(&ids, "select id from t"); err != nil || !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+			t.Fatalf("rawScan %v: %v, %v", rawScan, ids, err)
+		}
+		var names []string
+		if _, err := db.Select(&names, "select id from t"); err != nil || !reflect.DeepEqual(names, []string{"1", "2", "3"}) {
+			t.Fatalf("rawScan %v: %v, %v", rawScan, names, err)
+		}
+	}
+	var ids []int64
+	if _, err := db.Select(&ids, "select id, name from t"); err == nil {
+		t.Fatalf("scalar slice with 2 columns should fail")
+	}
+}
+
+func TestRowsToMap(t *testing.T) {
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select name, age from t", fakeResultSet{[]string{"name", "age"}, [][]driver.Value{{"a", int64(1)}, {[]byte("b"), []byte("2")}}})
+	server.respond("select id, name, age from t", fakeResultSet{[]string{"id", "name", "age"}, nil})
+	for _, rawScan := range []bool{true, false} {
+		db.SetRawScan(rawScan)
+		m := map[string]int{"stale": 3}
+		if _, err := db.Select(&m, "select name, age from t"); err != nil || !reflect.DeepEqual(m, map[string]int{"a": 1, "b": 2}) {
+			t.Fatalf("rawScan %v: %v, %v", rawScan, m, err)
+		}
+	}
+	var m map[string]int
+	if _, err := db.Select(&m, "select id, name, age from t"); err == nil {
+		t.Fatalf("map with 3 columns should fail")
+	}
+}
+
+func TestRowsToKeyedMap(t *testing.T) {
+	type Order struct {
+		Id     int64  `db:"id"`
+		UserId int64  `db:"user_id,key"`
+		Note   string `db:"note"`
+	}
+	type Plain struct {
+		Id     int64  `db:"id"`
+		UserId int64  `db:"user_id"`
+		Note   string `db:"note"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select id, user_id, note from orders", fakeResultSet{
+		[]string{"id", "user_id", "note"},
+		[][]driver.Value{{int64(1), int64(10), "a"}, {int64(2), int64(20), "b"}, {int64(3), int64(10), "c"}},
+	})
+	server.respond("select id, note from orders", fakeResultSet{
+		[]string{"id", "note"},
+		[][]driver.Value{{int64(1), "a"}},
+	})
+
+	for _, rawScan := range []bool{true, false} {
+		db.SetRawScan(rawScan)
+
+		// the key tag option without KeyBy
+		var byUser map[int64][]*Order
+		if _, err := db.Select(&byUser, "select id, user_id, note from orders"); err != nil {
+			t.Fatalf("rawScan %v: %v", rawScan, err)
+		}
+		if len(byUser) != 2 || len(byUser[10]) != 2 || len(byUser[20]) != 1 ||
+			*byUser[10][0] != (Order{1, 10, "a"}) || *byUser[10][1] != (Order{3, 10, "c"}) || *byUser[20][0] != (Order{2, 20, "b"}) {
+			t.Fatalf("rawScan %v: %+v", rawScan, byUser)
+		}
+
+		var byId map[int64]*Plain
+		if _, err := db.Select(&byId, "select id, user_id, note from orders", KeyBy("id")); err != nil {
+			t.Fatalf("rawScan %v: %v", rawScan, err)
+		}
+		if len(byId) != 3 || *byId[1] != (Plain{1, 10, "a"}) || *byId[2] != (Plain{2, 20, "b"}) || *byId[3] != (Plain{3, 10, "c"}) {
+			t.Fatalf("rawScan %v: %+v", rawScan, byId)
+		}
+
+		// the key column isn't mapped to the fields
+		type Note struct {
+			Note string `db:"note"`
+		}
+		var notesByUser map[int64][]Note
+		if _, err := db.Select(&notesByUser, "select id, user_id, note from orders", KeyBy("user_id")); err != nil ||
+			!reflect.DeepEqual(notesByUser, map[int64][]Note{10: {{"a"}, {"c"}}, 20: {{"b"}}}) {
+			t.Fatalf("rawScan %v: %+v, %v", rawScan, notesByUser, err)
+		}
+
+		var byNote map[string]Plain
+		if _, err := db.Select(&byNote, "select id, user_id, note from orders", KeyBy("note")); err != nil || len(byNote) != 3 || byNote["b"] != (Plain{2, 20, "b"}) {
+			t.Fatalf("rawScan %v: %+v, %v", rawScan, byNote, err)
+		}
+	}
+
+	var noKey map[int64]*Plain
+	if _, err := db.Select(&noKey, "select id, user_id, note from orders"); err == nil || !strings.Contains(err.Error(), "no key column") {
+		t.Fatalf("no key column: %v", err)
+	}
+	var missing map[int64][]*Order
+	if _, err := db.Select(&missing, "select id, note from orders"); err == nil || !strings.Contains(err.Error(), "key column user_id not found") {
+		t.Fatalf("missing key column: %v", err)
+	}
+	var badKey map[int64]*Plain
+	if _, err := db.Select(&badKey, "select id, user_id, note from orders", KeyBy("note")); err == nil || !strings.Contains(err.Error(), "not convertible") {
+		t.Fatalf("key type: %v", err)
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	type User struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select id, name, extra from users", fakeResultSet{[]string{"id", "name", "extra"}, [][]driver.Value{{int64(1), "a", "x"}}})
+	var users []User
+	if _, err := db.Select(&users, "select id, name, extra from users"); err != nil {
+		t.Fatalf("no check: %v", err)
+	}
+	tests := []struct {
+		check    ColumnCheck
+		unmapped []string
+		missing  []string
+	}{
+		{CheckUnmappedColumns, []string{"extra"}, nil},
+		{CheckMissingFields, nil, []string{"Age(age)"}},
+		{CheckAllColumns, []string{"extra"}, []string{"Age(age)"}},
+	}
+	for _, tt := range tests {
+		// run twice to hit the cached mismatches
+		for i := 0; i < 2; i++ {
+			var user User
+			_, err := db.QueryRow(&user, "select id, name, extra from users", CheckColumns(tt.check))
+			mappingErr, ok := err.(*ColumnMappingError)
+			if !ok || !reflect.DeepEqual(mappingErr.UnmappedColumns, tt.unmapped) || !reflect.DeepEqual(mappingErr.MissingFields, tt.missing) {
+				t.Fatalf("check %v: %#v", tt.check, err)
+			}
+		}
+	}
+
+	type Order struct {
+		Id     int64 `db:"id"`
+		Amount int64 `db:"amount"`
+	}
+	type Buyer struct {
+		Id     int64   `db:"id"`
+		Orders []Order `join:"o"`
+	}
+	server.respond("select joined", fakeResultSet{[]string{"id", "o.id", "o.extra"}, [][]driver.Value{{int64(1), int64(2), int64(3)}}})
+	var buyers []Buyer
+	_, err := db.Select(&buyers, "select joined", CheckColumns(CheckAllColumns))
+	mappingErr, ok := err.(*ColumnMappingError)
+	if !ok || !reflect.DeepEqual(mappingErr.UnmappedColumns, []string{"o.extra"}) || !reflect.DeepEqual(mappingErr.MissingFields, []string{"Orders.Amount(amount)"}) {
+		t.Fatalf("join check: %#v", err)
+	}
+	if _, err = db.Select(&buyers, "select joined"); err != nil || len(buyers) != 1 || len(buyers[0].Orders) != 1 {
+		t.Fatalf("join without check: %+v, %v", buyers, err)
+	}
+}
+
+func Test_prepareSlice(t *testing.T) {
+	s := make([]int, 2, 3)
+	v := reflect.ValueOf(&s).Elem()
+	prepareSlice(v, &queryOptions{})
+	if len(s) != 0 || cap(s) != 3 {
+		t.Fatalf("truncate: len %v, cap %v", len(s), cap(s))
+	}
+	s = append(s, 1)
+	prepareSlice(v, &queryOptions{appendRows: true, capacity: 10})
+	if !reflect.DeepEqual(s, []int{1}) || cap(s) < 11 {
+		t.Fatalf("append: %v, cap %v", s, cap(s))
+	}
+}
+
+func Test_reusableElem(t *testing.T) {
+	a, b := &struct{ N int }{1}, &struct{ N int }{2}
+	s := []*struct{ N int }{a, b}[:1]
+	v := reflect.ValueOf(&s).Elem()
+	elem := reusableElem(v)
+	if !elem.IsValid() || elem.Addr().Interface() != b || b.N != 0 {
+		t.Fatalf("reusableElem should return the zeroed b")
+	}
+	s = append(s, nil)[:1]
+	if reusableElem(v).IsValid() {
+		t.Fatalf("reusableElem should skip nil pointers")
+	}
+	s = s[:1:1]
+	if reusableElem(v).IsValid() {
+		t.Fatalf("reusableElem should fail without capacity")
+	}
+}
+
+func TestQueryOptions(t *testing.T) {
+	type Order struct {
+		Id int64 `db:"id"`
+	}
+	type User struct {
+		Id     int64   `db:"id"`
+		Orders []Order `join:"o"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select joined", fakeResultSet{[]string{"id", "o.id"}, [][]driver.Value{{int64(1), int64(10)}, {int64(1), int64(11)}}})
+	server.respond("select keyed", fakeResultSet{[]string{"id"}, [][]driver.Value{{int64(2)}}})
+
+	old := &User{Id: 9}
+	users := []*User{old}
+	if _, err := db.Select(&users, "select joined", Append()); err != nil || len(users) != 2 || users[0] != old || len(users[1].Orders) != 2 {
+		t.Fatalf("joined append: %+v, %v", users, err)
+	}
+	users = users[:0]
+	if _, err := db.Select(&users, "select joined", Reuse()); err != nil || len(users) != 1 || users[0] != old || users[0].Id != 1 || len(users[0].Orders) != 2 {
+		t.Fatalf("joined reuse: %+v, %v", users, err)
+	}
+
+	keyed := map[int64]Order{1: {1}}
+	if _, err := db.Select(&keyed, "select keyed", KeyBy("id"), Append()); err != nil || len(keyed) != 2 {
+		t.Fatalf("keyed append: %v, %v", keyed, err)
+	}
+	if _, err := db.Select(&keyed, "select keyed", KeyBy("id")); err != nil || len(keyed) != 1 {
+		t.Fatalf("keyed: %v, %v", keyed, err)
+	}
+	var sized map[int64]Order
+	if _, err := db.Select(&sized, "select keyed", KeyBy("id"), Capacity(8)); err != nil || len(sized) != 1 {
+		t.Fatalf("keyed capacity: %v, %v", sized, err)
+	}
+	if _, err := db.Select(&keyed, "select keyed", KeyBy("id"), Reuse()); err == nil {
+		t.Fatalf("keyed reuse should fail")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeFunc converts src, the value returned by the driver or nil for NULL, to a
+// value of the registered type. The []byte src is copied and can be retained.
+type DecodeFunc func(src interface{}) (interface{}, error)
+
+// EncodeFunc converts v of the registered type to a value supported by the driver.
+type EncodeFunc func(v interface{}) (interface{}, error)
+
+type converter struct {
+	decode DecodeFunc
+	encode EncodeFunc
+}
+
+// RegisterConverter registers the functions converting typ from the column values when
+// selecting, and to the args when inserting or updating, for the types that don't
+// implement sql.Scanner or driver.Valuer. Either of them can be nil.
+// The fields of typ are always converted by Field even if raw scan is enabled.
+func (db *DB) RegisterConverter(typ reflect.Type, decode DecodeFunc, encode EncodeFunc) {
+	db.convertOptions.converters.Store(typ, &converter{decode: decode, encode: encode})
+	db.resetFieldConvertOptions()
+}
+
+// resetFieldConvertOptions drops the cached field options, which depend on the
+// registered converters and serializers.
+func (db *DB) resetFieldConvertOptions() {
+	db.mapping.Range(func(k, v interface{}) bool {
+		if _, ok := k.(fieldConvertOptionsKey); ok {
+			db.mapping.Delete(k)
+		}
+		return true
+	})
+}
+
+func (opts *convertOptions) getConverter(typ reflect.Type) *converter {
+	if opts.converters == nil {
+		return nil
+	}
+	if c, ok := opts.converters.Load(typ); ok {
+		return c.(*converter)
+	}
+	return nil
+}
+
+func (db *DB) getConverter(typ reflect.Type) *converter {
+	return db.convertOptions.getConverter(typ)
+}
+
+// needsField reports whether the values of typ can't be scanned by the driver
+// and should be converted by Field.
+func (db *DB) needsField(typ reflect.Type) bool {
+	if isBigType(typ) {
+		return true
+	}
+	if db.getConverter(typ) != nil {
+		return true
+	}
+	return typ.Kind() == reflect.Ptr && db.getConverter(typ.Elem()) != nil
+}
+
+func (c *converter) decodeTo(src interface{}, dstVal reflect.Value) error {
+	if b, ok := src.([]byte); ok {
+		src = append([]byte{}, b...)
+	}
+	v, err := c.decode(src)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		dstVal.Set(reflect.Zero(dstVal.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type().AssignableTo(dstVal.Type()):
+		dstVal.Set(rv)
+	case rv.Type().ConvertibleTo(dstVal.Type()):
+		dstVal.Set(rv.Convert(dstVal.Type()))
+	default:
+		return fmt.Errorf("decoded value of type %v is not assignable to %v", rv.Type(), dstVal.Type())
+	}
+	return nil
+}
+
+// encodeValue returns the value passed to the driver for v, encoded by the registered
+// converter of its type if any.
+func (db *DB) encodeValue(v reflect.Value) (interface{}, error) {
+	c := db.getConverter(v.Type())
+	if c == nil && v.Kind() == reflect.Ptr {
+		if c = db.getConverter(v.Type().Elem()); c != nil {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+	}
+	if c == nil || c.encode == nil {
+		return v.Interface(), nil
+	}
+	return c.encode(v.Interface())
+}
+
+func (db *DB) encodeArgs(args []interface{}) ([]interface{}, error) {
+	var encoded []interface{}
+	for i, arg := range args {
+		if arg == nil || db.getConverter(reflect.TypeOf(arg)) == nil {
+			continue
+		}
+		if encoded == nil {
+			encoded = append(make([]interface{}, 0, len(args)), args...)
+		}
+		v, err := db.encodeValue(reflect.ValueOf(arg))
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = v
+	}
+	if encoded == nil {
+		return args, nil
+	}
+	return encoded, nil
+}
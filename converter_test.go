@@ -0,0 +1,65 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type convPoint struct{ X, Y int }
+
+func TestRegisterConverter(t *testing.T) {
+	type Shape struct {
+		Id     int64      `db:"id"`
+		Center convPoint  `db:"center"`
+		Corner *convPoint `db:"corner"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	db.RegisterConverter(reflect.TypeOf(convPoint{}),
+		func(src interface{}) (interface{}, error) {
+			if src == nil {
+				return nil, nil
+			}
+			var p convPoint
+			_, err := fmt.Sscanf(string(src.([]byte)), "%d,%d", &p.X, &p.Y)
+			return p, err
+		},
+		func(v interface{}) (interface{}, error) {
+			p := v.(convPoint)
+			return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+		},
+	)
+
+	if _, err := db.Insert("insert into shapes", &Shape{1, convPoint{1, 2}, nil}); err != nil {
+		t.Fatal(err)
+	}
+	if last := server.last(); !reflect.DeepEqual(last.args, []driver.Value{int64(1), "1,2", nil}) {
+		t.Fatalf("insert args: %#v", last.args)
+	}
+
+	_, err := db.Update("update shapes set center=?, corner=? where center=?", &Shape{Center: convPoint{3, 4}, Corner: &convPoint{5, 6}}, convPoint{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := server.last(); !reflect.DeepEqual(last.args, []driver.Value{"3,4", "5,6", "1,2"}) {
+		t.Fatalf("update args: %#v", last.args)
+	}
+
+	server.respond("select * from shapes", fakeResultSet{[]string{"id", "center", "corner"}, [][]driver.Value{
+		{int64(1), []byte("3,4"), []byte("5,6")},
+		{int64(2), []byte("7,8"), nil},
+	}})
+	want := []Shape{{1, convPoint{3, 4}, &convPoint{5, 6}}, {2, convPoint{7, 8}, nil}}
+	for _, rawScan := range []bool{true, false} {
+		db.SetRawScan(rawScan)
+		var shapes []Shape
+		if _, err = db.Select(&shapes, "select * from shapes"); err != nil || !reflect.DeepEqual(shapes, want) {
+			t.Fatalf("rawScan %v: %+v, %v", rawScan, shapes, err)
+		}
+	}
+}
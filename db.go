@@ -11,49 +11,88 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type DB struct {
 	*sql.DB
-	tag                string
+	tags               []string
+	tagExtractors      map[string]TagExtractor
 	quote              string
 	placeholder        string
 	placeholderBuilder func(int) string
+	callBuilder        CallBuilder
+	callOutMode        callOutMode
+	interceptors       []Interceptor
+	tracer             Tracer
+	statsCollector     *StatsCollector
+	slowThreshold      time.Duration
+	slowQueryLog       func(SlowQuery)
+	logger             Logger
+	logLevel           LogLevel
+	logSampling        float64
+	printSql           func(string)
+	redactColumns      []string
+	redactFunc         func(column string, value interface{}) bool
+	interpolateParams  bool
+	noBackslashEscapes bool
+	charset            string
+	commentTags        map[string]string
+	commentExtractors  []CommentExtractor
 	rawScan            bool
+	columnCheck        ColumnCheck
+	convertOptions     convertOptions
+	serializers        map[string]Serializer
 	mapping            *sync.Map
 	fieldNameParser    FieldParser
+	naming             NamingStrategy
+	caseInsensitive    bool
 
-	ctx      context.Context
-	cancel   func()
-	isMysql  bool
-	printSql func(string)
+	ctx     context.Context
+	cancel  func()
+	isMysql bool
+	dialect string
 }
 
 func (db *DB) Begin() (*Tx, error) {
-	tx, err := db.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{
-		DB: db,
-		Tx: tx,
-	}, nil
+	return db.BeginTx(db.ctx, nil)
 }
 
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
-	tx, err := db.DB.BeginTx(ctx, opts)
+	var span Span
+	if db.tracer != nil {
+		ctx, span = db.tracer.StartSpan(ctx, spanNameTx)
+	}
+	var tx *sql.Tx
+	info := newQueryInfo(db.DB, nil, opTypBegin, opTypBegin, nil)
+	_, err := db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		var err error
+		tx, err = db.DB.BeginTx(ctx, opts)
+		return nil, err
+	})
+	if err == nil && tx == nil {
+		err = fmt.Errorf("[sqlw %v] transaction not begun by the interceptors", opTypBegin)
+	}
 	if err != nil {
+		if span != nil {
+			span.RecordError(err)
+			span.End()
+		}
 		return nil, err
 	}
-	return &Tx{
-		DB: db,
-		Tx: tx,
-	}, nil
+	t := &Tx{
+		DB:   db,
+		Tx:   tx,
+		id:   atomic.AddUint64(&lastTxID, 1),
+		span: span,
+	}
+	t.spanCtx = context.WithValue(ctx, txSpanKey{}, t)
+	return t, nil
 }
 
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
-	result, err := db.DB.ExecContext(ctx, query, args...)
-	return newResult(db, result, query, args, false), err
+	return execContext(db, ctx, db.DB, nil, opTypExec, query, args, nil)
 }
 
 func (db *DB) Exec(query string, args ...interface{}) (Result, error) {
@@ -65,15 +104,33 @@ func (db *DB) Prepare(query string) (*Stmt, error) {
 }
 
 func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	stmt, err := db.DB.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	return NewStmt(db, stmt, query), nil
+	return prepareContext(db, ctx, db.DB, db.DB, query)
+}
+
+// CallContext calls the stored procedure with the tagged fields of in and out as the parameters,
+// out must be a struct pointer and its fields are set to the OUT parameters, the returned result
+// sets are mapped into dsts in order. The OUT parameters are bound by sql.Out for SQL Server and
+// Oracle, passed as the user variables selected after the call for MySQL, and mapped from the row
+// returned by the call for PostgreSQL.
+func (db *DB) CallContext(ctx context.Context, proc string, in, out interface{}, dsts ...interface{}) (Result, error) {
+	return callContext(db, ctx, db.DB, proc, in, out, dsts...)
+}
+
+func (db *DB) Call(proc string, in, out interface{}, dsts ...interface{}) (Result, error) {
+	return db.CallContext(db.ctx, proc, in, out, dsts...)
+}
+
+// QueryMultiContext maps the result sets of a stored procedure or multi-statement query into dsts in order.
+func (db *DB) QueryMultiContext(ctx context.Context, dsts []interface{}, query string, args ...interface{}) (Result, error) {
+	return queryMultiContext(db, ctx, db.DB, dsts, query, args...)
+}
+
+func (db *DB) QueryMulti(dsts []interface{}, query string, args ...interface{}) (Result, error) {
+	return db.QueryMultiContext(db.ctx, dsts, query, args...)
 }
 
 func (db *DB) QueryRowContext(ctx context.Context, dst interface{}, query string, args ...interface{}) (Result, error) {
-	return queryRowContext(db, ctx, db.DB, db.parseFieldName, dst, db.mapping, db.rawScan, query, args...)
+	return queryRowContext(db, ctx, db.DB, dst, query, args...)
 }
 
 func (db *DB) QueryRow(dst interface{}, query string, args ...interface{}) (Result, error) {
@@ -81,7 +138,7 @@ func (db *DB) QueryRow(dst interface{}, query string, args ...interface{}) (Resu
 }
 
 func (db *DB) QueryContext(ctx context.Context, dst interface{}, query string, args ...interface{}) (Result, error) {
-	return queryContext(db, ctx, db.DB, db.parseFieldName, dst, db.mapping, db.rawScan, query, args...)
+	return queryContext(db, ctx, db.DB, dst, query, args...)
 }
 
 func (db *DB) Query(dst interface{}, query string, args ...interface{}) (Result, error) {
@@ -110,6 +167,16 @@ func (db *DB) Select(dst interface{}, query string, args ...interface{}) (Result
 // 	return db.SelectOneContext(db.ctx, dst, query, args...)
 // }
 
+// PreloadContext loads the relations declared by the rel tag of the fields for dst, which
+// should be a pointer to a struct or a slice of structs, with one query for each field.
+func (db *DB) PreloadContext(ctx context.Context, dst interface{}, fields ...string) error {
+	return preloadContext(db, ctx, db.DB, dst, fields...)
+}
+
+func (db *DB) Preload(dst interface{}, fields ...string) error {
+	return db.PreloadContext(db.ctx, dst, fields...)
+}
+
 func (db *DB) InsertContext(ctx context.Context, sqlHead string, args ...interface{}) (Result, error) {
 	return insertContext(ctx, db.DB, nil, sqlHead, db, args...)
 }
@@ -127,8 +194,7 @@ func (db *DB) Update(sqlHead string, args ...interface{}) (Result, error) {
 }
 
 func (db *DB) DeleteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
-	result, err := db.DB.ExecContext(ctx, query, args...)
-	return newResult(db, result, query, args, false), err
+	return execContext(db, ctx, db.DB, nil, opTypDelete, query, args, nil)
 }
 
 func (db *DB) Delete(query string, args ...interface{}) (Result, error) {
@@ -145,12 +211,24 @@ func (db *DB) Close() error {
 	return err
 }
 
+// Tag returns the first tag.
 func (db *DB) Tag() string {
-	return db.tag
+	if len(db.tags) == 0 {
+		return ""
+	}
+	return db.tags[0]
+}
+
+func (db *DB) Tags() []string {
+	return db.tags
 }
 
-func (db *DB) SetTag(tag string) {
-	db.tag = tag
+// SetTag sets the tags used to parse the field names, in order, such as SetTag("db", "gorm", "json").
+// The name comes from the first tag with a name, and the options of the tags before it are merged,
+// such as "json" for `db:",json" gorm:"column:meta"`.
+func (db *DB) SetTag(tags ...string) {
+	db.tags = tags
+	db.resetMapping()
 }
 
 func (db *DB) Placeholder() string {
@@ -200,11 +278,92 @@ func (db *DB) SetRawScan(rawScan bool) {
 	db.rawScan = rawScan
 }
 
+// ColumnCheck decides which mismatches between the result columns and the struct fields are reported.
+type ColumnCheck int
+
+const (
+	// CheckUnmappedColumns reports the result columns without destination fields.
+	CheckUnmappedColumns ColumnCheck = 1 << iota
+	// CheckMissingFields reports the tagged fields without result columns.
+	CheckMissingFields
+	// CheckAllColumns reports both.
+	CheckAllColumns = CheckUnmappedColumns | CheckMissingFields
+)
+
+func (db *DB) ColumnCheck() ColumnCheck {
+	return db.columnCheck
+}
+
+// SetColumnCheck makes selecting into structs return *ColumnMappingError for the mismatches
+// reported by check, 0 disables it.
+func (db *DB) SetColumnCheck(check ColumnCheck) {
+	db.columnCheck = check
+}
+
+func (db *DB) StrictConvert() bool {
+	return db.convertOptions.strict
+}
+
+// SetStrictConvert makes the conversions return *ConvertError when the column values
+// are invalid or overflow the fields instead of truncating them or setting them to zero.
+// It works when raw scan is disabled, the driver always reports these errors in raw scan mode.
+func (db *DB) SetStrictConvert(strict bool) {
+	db.convertOptions.strict = strict
+}
+
+func (db *DB) TimeLayouts() []string {
+	if len(db.convertOptions.layouts) == 0 {
+		return defaultTimeLayouts
+	}
+	return db.convertOptions.layouts
+}
+
+// SetTimeLayouts sets the layouts tried in order when converting string values to time.Time,
+// the default is "2006-01-02 15:04:05". A field can use its own layout by the "layout" tag option.
+// The DB level time settings work when raw scan is disabled, the tag options work in both modes.
+func (db *DB) SetTimeLayouts(layouts ...string) {
+	db.convertOptions.layouts = layouts
+}
+
+func (db *DB) TimeLocation() *time.Location {
+	if db.convertOptions.location == nil {
+		return time.UTC
+	}
+	return db.convertOptions.location
+}
+
+// SetTimeLocation sets the location of the time values without time zone, the default is UTC.
+func (db *DB) SetTimeLocation(location *time.Location) {
+	db.convertOptions.location = location
+}
+
+func (db *DB) EpochUnit() EpochUnit {
+	return db.convertOptions.epoch
+}
+
+// SetEpochUnit sets the unit of the integer values converted to time.Time, the default is EpochSecond.
+// A field can use its own unit by the "epoch" tag option with value "s", "ms", "us" or "ns".
+func (db *DB) SetEpochUnit(unit EpochUnit) {
+	db.convertOptions.epoch = unit
+}
+
 func (db *DB) parseFieldName(field *reflect.StructField) string {
 	if db.fieldNameParser != nil {
 		return db.fieldNameParser(field)
 	}
-	return field.Tag.Get(db.tag)
+	name, _ := db.lookupTag(field)
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return db.fieldNameByNaming(field)
+	}
+	return name
+}
+
+func (db *DB) parseFieldOptions(field *reflect.StructField) tagOptions {
+	_, opts := db.lookupTag(field)
+	return opts
 }
 
 var nilContext context.Context
@@ -228,15 +387,22 @@ func Wrap(db *sql.DB, driverName, tag string) *DB {
 func WrapContext(ctx context.Context, db *sql.DB, driverName, tag string) *DB {
 	sqlwDB := &DB{
 		DB:                 db,
-		tag:                tag,
+		tags:               []string{tag},
+		tagExtractors:      defaultTagExtractors(),
 		placeholder:        "?",
 		placeholderBuilder: func(int) string { return "?" },
+		callBuilder:        defaultCallBuilder(driverName),
+		callOutMode:        defaultCallOutMode(driverName),
 		rawScan:            true,
 		mapping:            &sync.Map{},
 		ctx:                ctx,
 		cancel:             func() {},
 		isMysql:            true,
+		dialect:            dialectOf(driverName),
+		logLevel:           LevelInfo,
+		serializers:        defaultSerializers(),
 	}
+	sqlwDB.convertOptions.converters = &sync.Map{}
 	if !strings.Contains(driverName, "mysql") {
 		sqlwDB.isMysql = false
 		sqlwDB.placeholder = "$"
@@ -249,7 +415,3 @@ func WrapContext(ctx context.Context, db *sql.DB, driverName, tag string) *DB {
 	}
 	return sqlwDB
 }
-
-func (db *DB) PrintSql(f func(string)) {
-	db.printSql = f
-}
@@ -0,0 +1,118 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// isBigType reports whether t is big.Int, big.Float, big.Rat or a pointer to them,
+// which are not supported by the drivers and always converted by Field.
+func isBigType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == bigIntType || t == bigFloatType || t == bigRatType
+}
+
+// toBig sets big.Int, big.Float or big.Rat from the text of the value, so DECIMAL
+// columns never pass through float64 unless the driver returns float64.
+func (field *Field) toBig(dstVal reflect.Value, opts *convertOptions) error {
+	var s string
+	switch field.typ {
+	case reflect.Int64, reflect.Float64, reflect.Slice, reflect.String:
+		s = field.String()
+	default:
+		if opts.strict {
+			return errUnsupportedConversion(field.typ, dstVal.Type().String())
+		}
+		return nil
+	}
+
+	switch dst := dstVal.Addr().Interface().(type) {
+	case *big.Int:
+		if _, ok := dst.SetString(s, 10); ok {
+			return nil
+		}
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			if opts.strict {
+				return fmt.Errorf("invalid integer value %q", s)
+			}
+			dst.SetInt64(0)
+			return nil
+		}
+		if !r.IsInt() && opts.strict {
+			return fmt.Errorf("value %v is not an integer", s)
+		}
+		dst.Quo(r.Num(), r.Denom())
+	case *big.Float:
+		if _, ok := dst.SetString(s); !ok {
+			if opts.strict {
+				return fmt.Errorf("invalid float value %q", s)
+			}
+			dst.SetInt64(0)
+		}
+	case *big.Rat:
+		if _, ok := dst.SetString(s); !ok {
+			if opts.strict {
+				return fmt.Errorf("invalid rational value %q", s)
+			}
+			dst.SetInt64(0)
+		}
+	}
+	return nil
+}
+
+// Decimal keeps the text of DECIMAL/NUMERIC values as they are, for money values
+// that should never pass through float64. Use *Decimal for nullable columns.
+type Decimal string
+
+// Scan implements sql.Scanner.
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		*d = Decimal(v)
+	case string:
+		*d = Decimal(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+	case float64:
+		*d = Decimal(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		*d = ""
+	default:
+		return fmt.Errorf("[sqlw] unsupported conversion from %T to Decimal", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Decimal) Value() (driver.Value, error) {
+	return string(d), nil
+}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// Rat returns the exact value of d.
+func (d Decimal) Rat() (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return nil, fmt.Errorf("[sqlw] invalid decimal value %q", string(d))
+	}
+	return r, nil
+}
@@ -4,6 +4,41 @@
 
 package sqlw
 
+import "fmt"
+
 // var (
 // 	ErrNotFound = errors.New("not found")
 // )
+
+// ConvertError is returned when a column value can't be set to the destination.
+type ConvertError struct {
+	Column string
+	Field  string
+	Value  interface{}
+	Err    error
+}
+
+func (e *ConvertError) Error() string {
+	value := e.Value
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	return fmt.Sprintf("[sqlw %v] convert column %v to %v failed: %v, raw value: %#v", opTypSelect, e.Column, e.Field, e.Err, value)
+}
+
+func (e *ConvertError) Unwrap() error {
+	return e.Err
+}
+
+// ColumnMappingError is returned by the column checks set by DB.SetColumnCheck or sqlw.CheckColumns.
+type ColumnMappingError struct {
+	Type string
+	// UnmappedColumns are the result columns without destination fields.
+	UnmappedColumns []string
+	// MissingFields are the tagged fields without result columns, formatted as "Field(column)".
+	MissingFields []string
+}
+
+func (e *ColumnMappingError) Error() string {
+	return fmt.Sprintf("[sqlw %v] columns of %v mismatch, columns without fields: %v, fields without columns: %v", opTypSelect, e.Type, e.UnmappedColumns, e.MissingFields)
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver is a minimal driver.Driver for the tests, the statements are recorded by
+// fakeServer and the queries return the result sets set by fakeServer.respond. The
+// statements containing "fail" return errors.
+type fakeDriver struct{}
+
+type fakeResultSet struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+type fakeStatement struct {
+	query string
+	args  []driver.Value
+}
+
+type fakeServer struct {
+	mu        sync.Mutex
+	responses map[string][]fakeResultSet
+	stmts     []fakeStatement
+}
+
+var (
+	fakeServersMux sync.Mutex
+	fakeServers    = map[string]*fakeServer{}
+)
+
+func init() {
+	sql.Register("fakemysql", fakeDriver{})
+	sql.Register("fakepostgres", fakeDriver{})
+}
+
+// newFakeDB opens a DB with the "db" tag on a new fakeServer, the driver name containing
+// "mysql" makes the DB use the MySQL dialect.
+func newFakeDB(t *testing.T, driverName string) (*DB, *fakeServer) {
+	server := &fakeServer{responses: map[string][]fakeResultSet{}}
+	fakeServersMux.Lock()
+	dsn := fmt.Sprintf("%v-%v", t.Name(), len(fakeServers))
+	fakeServers[dsn] = server
+	fakeServersMux.Unlock()
+	db, err := Open(driverName, dsn, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, server
+}
+
+func (s *fakeServer) respond(query string, sets ...fakeResultSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[query] = sets
+}
+
+// last returns the last recorded statement.
+func (s *fakeServer) last() fakeStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.stmts) == 0 {
+		return fakeStatement{}
+	}
+	return s.stmts[len(s.stmts)-1]
+}
+
+// queries returns the recorded statements.
+func (s *fakeServer) queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries := make([]string, len(s.stmts))
+	for i, stmt := range s.stmts {
+		queries[i] = stmt.query
+	}
+	return queries
+}
+
+func (s *fakeServer) record(query string, args []driver.Value) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stmts = append(s.stmts, fakeStatement{query: query, args: args})
+	if strings.Contains(query, "fail") {
+		return fmt.Errorf("fake failed: %v", query)
+	}
+	return nil
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	fakeServersMux.Lock()
+	defer fakeServersMux.Unlock()
+	server, ok := fakeServers[dsn]
+	if !ok {
+		return nil, fmt.Errorf("no fake server: %v", dsn)
+	}
+	return &fakeConn{server}, nil
+}
+
+type fakeConn struct{ server *fakeServer }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.server, query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return &fakeTx{c.server}, c.server.record("begin", nil)
+}
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type fakeTx struct{ server *fakeServer }
+
+func (tx *fakeTx) Commit() error   { return tx.server.record("commit", nil) }
+func (tx *fakeTx) Rollback() error { return tx.server.record("rollback", nil) }
+
+type fakeStmt struct {
+	server *fakeServer
+	query  string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.server.record(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.server.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	sets, ok := s.server.responses[s.query]
+	if !ok {
+		return nil, fmt.Errorf("no fake response: %v", s.query)
+	}
+	return &fakeRows{sets: sets}, nil
+}
+
+type fakeRows struct {
+	sets []fakeResultSet
+	set  int
+	row  int
+}
+
+func (r *fakeRows) Columns() []string { return r.sets[r.set].cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	set := r.sets[r.set]
+	if r.row >= len(set.rows) {
+		return io.EOF
+	}
+	copy(dest, set.rows[r.row])
+	r.row++
+	return nil
+}
+func (r *fakeRows) HasNextResultSet() bool { return r.set+1 < len(r.sets) }
+func (r *fakeRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set++
+	r.row = 0
+	return nil
+}
@@ -5,6 +5,9 @@
 package sqlw
 
 import (
+	"database/sql"
+	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -69,121 +72,355 @@ func (field *Field) Scan(v interface{}) error {
 	return nil
 }
 
-var timeTypString = reflect.TypeOf(time.Time{}).String()
-
+// ToValue sets the field value to dstVal like ConvertTo, the errors are ignored.
 func (field *Field) ToValue(dstVal reflect.Value) {
+	field.toValue(dstVal, defaultConvertOptions)
+}
+
+// ConvertTo sets the field value to dstVal. NULL sets dstVal to nil for pointers and
+// to the zero value for others, pointers are allocated for non-NULL values, and types
+// implementing sql.Scanner are delegated to their Scan method.
+func (field *Field) ConvertTo(dstVal reflect.Value) error {
+	return field.toValue(dstVal, defaultConvertOptions)
+}
+
+// convertOptions controls the conversions of Field.toValue.
+type convertOptions struct {
+	// strict makes invalid values and overflows return errors instead of
+	// being truncated or set to zero.
+	strict bool
+	// layouts are tried in order to parse string values to time.Time,
+	// defaultTimeLayouts is used if it's empty.
+	layouts []string
+	// location is used for the string values without time zone, UTC if nil.
+	location *time.Location
+	// epoch is the unit of the integer values converted to time.Time.
+	epoch EpochUnit
+	// converters are registered by DB.RegisterConverter, reflect.Type -> *converter.
+	converters *sync.Map
+	// serializer is set by the field tag option, such as "json".
+	serializer Serializer
+}
+
+// EpochUnit is the unit of the integer timestamps.
+type EpochUnit int
+
+const (
+	EpochSecond EpochUnit = iota
+	EpochMillisecond
+	EpochMicrosecond
+	EpochNanosecond
+)
+
+func parseEpochUnit(s string) (EpochUnit, bool) {
+	switch s {
+	case "s":
+		return EpochSecond, true
+	case "ms":
+		return EpochMillisecond, true
+	case "us":
+		return EpochMicrosecond, true
+	case "ns":
+		return EpochNanosecond, true
+	default:
+	}
+	return EpochSecond, false
+}
+
+var defaultTimeLayouts = []string{"2006-01-02 15:04:05"}
+
+var defaultConvertOptions = &convertOptions{}
+
+func (field *Field) toValue(dstVal reflect.Value, opts *convertOptions) error {
+	dstTyp := dstVal.Type()
+	if opts.serializer != nil {
+		return field.unmarshalTo(opts.serializer, dstVal)
+	}
+	if c := opts.getConverter(dstTyp); c != nil && c.decode != nil {
+		return c.decodeTo(field.value(), dstVal)
+	}
+
+	if dstTyp.Kind() == reflect.Ptr {
+		if field.null {
+			dstVal.Set(reflect.Zero(dstTyp))
+			return nil
+		}
+		elemVal := reflect.New(dstTyp.Elem())
+		if err := field.toValue(elemVal.Elem(), opts); err != nil {
+			return err
+		}
+		dstVal.Set(elemVal)
+		return nil
+	}
+
+	if dstVal.CanAddr() && reflect.PtrTo(dstTyp).Implements(scannerType) {
+		return dstVal.Addr().Interface().(sql.Scanner).Scan(field.value())
+	}
+
+	if field.null {
+		dstVal.Set(reflect.Zero(dstTyp))
+		return nil
+	}
+
+	switch typ := dstTyp.Kind(); typ {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := field.ToInt64()
+		if opts.strict {
+			if err != nil {
+				return err
+			}
+			if dstVal.OverflowInt(v) {
+				return fmt.Errorf("value %v overflows %v", v, dstTyp)
+			}
+		}
+		dstVal.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := field.ToUint64()
+		if opts.strict {
+			if err != nil {
+				return err
+			}
+			if dstVal.OverflowUint(v) {
+				return fmt.Errorf("value %v overflows %v", v, dstTyp)
+			}
+		}
+		dstVal.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := field.ToFloat64()
+		if opts.strict {
+			if err != nil {
+				return err
+			}
+			if dstVal.OverflowFloat(v) {
+				return fmt.Errorf("value %v overflows %v", v, dstTyp)
+			}
+		}
+		dstVal.SetFloat(v)
+	case reflect.Bool:
+		if !opts.strict {
+			dstVal.SetBool(field.Bool())
+			break
+		}
+		v, err := field.ToBool()
+		if err != nil {
+			return err
+		}
+		dstVal.SetBool(v)
+	case reflect.Slice:
+		if dstTyp.Elem().Kind() == reflect.Uint8 {
+			// the driver may reuse the buffer for the next row
+			b := field.Bytes()
+			dstVal.SetBytes(append(make([]byte, 0, len(b)), b...))
+		}
+	case reflect.Array:
+		if dstTyp.Elem().Kind() == reflect.Uint8 {
+			reflect.Copy(dstVal, reflect.ValueOf(field.Bytes()))
+		}
+	case reflect.String:
+		dstVal.SetString(field.String())
+	case reflect.Struct:
+		switch dstTyp {
+		case timeType:
+			t, err := field.toTime(opts)
+			if err != nil && opts.strict {
+				return err
+			}
+			dstVal.Set(reflect.ValueOf(t))
+		case bigIntType, bigFloatType, bigRatType:
+			return field.toBig(dstVal, opts)
+		default:
+		}
+	case reflect.Interface:
+		if dstTyp.NumMethod() > 0 {
+			break
+		}
+		v := field.value()
+		if b, ok := v.([]byte); ok {
+			v = append(make([]byte, 0, len(b)), b...)
+		}
+		dstVal.Set(reflect.ValueOf(v))
+	default:
+	}
+	return nil
+}
+
+// value returns the value as it was received from the driver.
+func (field *Field) value() interface{} {
 	if !field.null {
-		switch typ := dstVal.Type().Kind(); typ {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			dstVal.SetInt(field.Int64())
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			dstVal.SetUint(field.Uint64())
-		case reflect.Float32, reflect.Float64:
-			dstVal.SetFloat(field.Float64())
+		switch field.typ {
+		case reflect.Int64:
+			return *(*int64)(field.ptr)
+		case reflect.Float64:
+			return *(*float64)(field.ptr)
 		case reflect.Bool:
-			dstVal.SetBool(field.Bool())
-		case reflect.Array, reflect.Slice:
-			dstVal.SetBytes(field.Bytes())
+			return *(*bool)(field.ptr)
+		case reflect.Slice: // *[]byte
+			return *(*[]byte)(field.ptr)
 		case reflect.String:
-			dstVal.SetString(field.String())
-		case reflect.Struct:
-			if dstVal.Type().String() == timeTypString {
-				t := field.Time()
-				dstVal.Set(reflect.ValueOf(t))
-			}
+			return *(*string)(field.ptr)
+		case reflect.Struct: // time.Time
+			return *(*time.Time)(field.ptr)
 		default:
 		}
 	}
+	return nil
 }
 
 func (field *Field) Int64() int64 {
+	v, _ := field.ToInt64()
+	return v
+}
+
+// ToInt64 is like Int64 but returns an error if the value is not an integer,
+// the fractional part of float values is truncated.
+func (field *Field) ToInt64() (int64, error) {
 	if !field.null {
 		switch field.typ {
 		case reflect.Int64:
-			return *(*int64)(field.ptr)
+			return *(*int64)(field.ptr), nil
 		case reflect.Float64:
-			return int64(*(*float64)(field.ptr))
+			return floatToInt64(*(*float64)(field.ptr))
 		case reflect.Bool:
 			if *(*bool)(field.ptr) {
-				return 1
+				return 1, nil
 			}
-			return 0
+			return 0, nil
 		case reflect.Slice: // *[]byte
-			s := string(*(*[]byte)(field.ptr))
-			if strings.Contains(s, ".") {
-				v, err := strconv.ParseFloat(s, 64)
-				if err != nil {
-					return 0
-				}
-				return int64(v)
-			}
-			v, err := strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				return 0
-			}
-			return v
+			return parseInt64(string(*(*[]byte)(field.ptr)))
 		case reflect.String:
-			s := *(*string)(field.ptr)
-			if strings.Contains(s, ".") {
-				v, err := strconv.ParseFloat(s, 64)
-				if err != nil {
-					return 0
-				}
-				return int64(v)
-			}
-			v, err := strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				return 0
-			}
-			return v
+			return parseInt64(*(*string)(field.ptr))
 		case reflect.Struct: // time.Time
 			// t := *(*time.Time)(field.ptr)
 			// return t.UnixNano()
+			return 0, errUnsupportedConversion(field.typ, "int64")
 		default:
 		}
 	}
 
-	return 0
+	return 0, nil
+}
+
+func parseInt64(s string) (int64, error) {
+	if strings.Contains(s, ".") {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return floatToInt64(v)
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+func floatToInt64(v float64) (int64, error) {
+	if v != math.Trunc(v) {
+		return int64(v), fmt.Errorf("value %v is not an integer", v)
+	}
+	if v < math.MinInt64 || v >= math.MaxInt64 {
+		return int64(v), fmt.Errorf("value %v overflows int64", v)
+	}
+	return int64(v), nil
 }
 
 func (field *Field) Uint64() uint64 {
-	return uint64(field.Int64())
+	v, _ := field.ToUint64()
+	return v
+}
+
+// ToUint64 is like Uint64 but returns an error if the value is not an unsigned integer.
+func (field *Field) ToUint64() (uint64, error) {
+	if !field.null {
+		switch field.typ {
+		case reflect.Int64:
+			v := *(*int64)(field.ptr)
+			if v < 0 {
+				return uint64(v), fmt.Errorf("value %v overflows uint64", v)
+			}
+			return uint64(v), nil
+		case reflect.Float64:
+			return floatToUint64(*(*float64)(field.ptr))
+		case reflect.Slice: // *[]byte
+			return parseUint64(string(*(*[]byte)(field.ptr)))
+		case reflect.String:
+			return parseUint64(*(*string)(field.ptr))
+		default:
+		}
+	}
+	v, err := field.ToInt64()
+	return uint64(v), err
+}
+
+func parseUint64(s string) (uint64, error) {
+	if strings.Contains(s, ".") {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return floatToUint64(v)
+	}
+	if strings.HasPrefix(s, "-") {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(v), fmt.Errorf("value %v overflows uint64", v)
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+func floatToUint64(v float64) (uint64, error) {
+	if v < 0 {
+		return uint64(int64(v)), fmt.Errorf("value %v overflows uint64", v)
+	}
+	if v != math.Trunc(v) {
+		return uint64(v), fmt.Errorf("value %v is not an integer", v)
+	}
+	if v >= math.MaxUint64 {
+		return uint64(v), fmt.Errorf("value %v overflows uint64", v)
+	}
+	return uint64(v), nil
 }
 
 func (field *Field) Float64() float64 {
+	v, _ := field.ToFloat64()
+	return v
+}
+
+// ToFloat64 is like Float64 but returns an error if the value is not a number.
+func (field *Field) ToFloat64() (float64, error) {
 	if !field.null {
 		switch field.typ {
 		case reflect.Int64:
-			return float64(*(*int64)(field.ptr))
+			return float64(*(*int64)(field.ptr)), nil
 		case reflect.Float64:
-			return *(*float64)(field.ptr)
+			return *(*float64)(field.ptr), nil
 		case reflect.Bool:
 			if *(*bool)(field.ptr) {
-				return 1
+				return 1, nil
 			}
-			return 0
+			return 0, nil
 		case reflect.Slice: // *[]byte
 			s := string(*(*[]byte)(field.ptr))
-			v, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				return 0
-			}
-			return v
+			return strconv.ParseFloat(s, 64)
 		case reflect.String:
 			s := *(*string)(field.ptr)
-			v, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				return 0
-			}
-			return v
+			return strconv.ParseFloat(s, 64)
 		case reflect.Struct: // time.Time
 			// t := *(*time.Time)(field.ptr)
 			// return float64(t.UnixNano())
+			return 0, errUnsupportedConversion(field.typ, "float64")
 		default:
 		}
 	}
 
-	return 0.0
+	return 0.0, nil
 }
 
 func (field *Field) Bool() bool {
@@ -221,15 +458,41 @@ func (field *Field) Bool() bool {
 	return false
 }
 
+// ToBool is like Bool but only accepts the strings supported by strconv.ParseBool.
+func (field *Field) ToBool() (bool, error) {
+	if !field.null {
+		switch field.typ {
+		case reflect.Slice, reflect.String:
+			s := field.String()
+			if s == "" {
+				return false, nil
+			}
+			return strconv.ParseBool(s)
+		case reflect.Struct: // time.Time
+			return false, errUnsupportedConversion(field.typ, "bool")
+		default:
+		}
+	}
+	return field.Bool(), nil
+}
+
+func errUnsupportedConversion(typ reflect.Kind, to string) error {
+	from := typ.String()
+	switch typ {
+	case reflect.Slice:
+		from = "[]byte"
+	case reflect.Struct:
+		from = "time.Time"
+	default:
+	}
+	return fmt.Errorf("unsupported conversion from %v to %v", from, to)
+}
+
 func (field *Field) Bytes() []byte {
 	if !field.null {
 		switch field.typ {
-		// case reflect.Int64:
-		// 	return []byte(fmt.Sprintf("%v", *(*int64)(field.ptr)))
-		// case reflect.Float64:
-		// 	return []byte(fmt.Sprintf("%v", *(*float64)(field.ptr)))
-		// case reflect.Bool:
-		// 	return []byte(fmt.Sprintf("%v", *(*bool)(field.ptr)))
+		case reflect.Int64, reflect.Float64, reflect.Bool:
+			return []byte(field.String())
 		case reflect.Slice: // *[]byte
 			return *(*[]byte)(field.ptr)
 		case reflect.String:
@@ -248,12 +511,12 @@ func (field *Field) Bytes() []byte {
 func (field *Field) String() string {
 	if !field.null {
 		switch field.typ {
-		// case reflect.Int64:
-		// 	return fmt.Sprintf("%v", *(*int64)(field.ptr))
-		// case reflect.Float64:
-		// 	return fmt.Sprintf("%v", *(*float64)(field.ptr))
-		// case reflect.Bool:
-		// 	return fmt.Sprintf("%v", *(*bool)(field.ptr))
+		case reflect.Int64:
+			return strconv.FormatInt(*(*int64)(field.ptr), 10)
+		case reflect.Float64:
+			return strconv.FormatFloat(*(*float64)(field.ptr), 'f', -1, 64)
+		case reflect.Bool:
+			return strconv.FormatBool(*(*bool)(field.ptr))
 		case reflect.Slice: // *[]byte
 			return string(*(*[]byte)(field.ptr))
 		case reflect.String:
@@ -269,38 +532,76 @@ func (field *Field) String() string {
 }
 
 func (field *Field) Time() time.Time {
+	t, _ := field.ToTime()
+	return t
+}
+
+// ToTime is like Time but returns an error if the value is not a valid time.
+func (field *Field) ToTime() (time.Time, error) {
+	return field.toTime(defaultConvertOptions)
+}
+
+func (field *Field) toTime(opts *convertOptions) (time.Time, error) {
 	if !field.null {
 		switch field.typ {
 		case reflect.Int64:
 			timestamp := *(*int64)(field.ptr)
-			t := time.Unix(timestamp, 0)
-			return t
+			var t time.Time
+			switch opts.epoch {
+			case EpochMillisecond:
+				t = time.Unix(timestamp/1e3, timestamp%1e3*1e6)
+			case EpochMicrosecond:
+				t = time.Unix(timestamp/1e6, timestamp%1e6*1e3)
+			case EpochNanosecond:
+				t = time.Unix(0, timestamp)
+			default:
+				t = time.Unix(timestamp, 0)
+			}
+			location := opts.location
+			if location == nil {
+				location = time.UTC
+			}
+			return t.In(location), nil
 		case reflect.Float64:
+			return time.Time{}, errUnsupportedConversion(field.typ, "time.Time")
 		case reflect.Bool:
+			return time.Time{}, errUnsupportedConversion(field.typ, "time.Time")
 		case reflect.Slice: // *[]byte
 			// YYYY-mm-dd HH:ii:ss
 			s := string(*(*[]byte)(field.ptr))
-			t, err := time.Parse("2006-01-02 15:04:05", s)
-			if err != nil {
-				return time.Time{}
-			}
-			return t
+			return parseTime(s, opts)
 		case reflect.String:
 			// YYYY-mm-dd HH:ii:ss
 			s := *(*string)(field.ptr)
-			t, err := time.Parse("2006-01-02 15:04:05", s)
-			if err != nil {
-				return time.Time{}
-			}
-			return t
+			return parseTime(s, opts)
 		case reflect.Struct: // time.Time
 			t := *(*time.Time)(field.ptr)
-			return t
+			return t, nil
 		default:
 		}
 	}
 
-	return time.Time{}
+	return time.Time{}, nil
+}
+
+func parseTime(s string, opts *convertOptions) (time.Time, error) {
+	layouts := opts.layouts
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+	}
+	location := opts.location
+	if location == nil {
+		location = time.UTC
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		t, err = time.ParseInLocation(layout, s, location)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
 }
 
 func releaseFields(fields []interface{}) {
@@ -0,0 +1,190 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordScanner struct{ v interface{} }
+
+func (s *recordScanner) Scan(src interface{}) error {
+	s.v = src
+	return nil
+}
+
+func TestField_ConvertTo(t *testing.T) {
+	var (
+		ptr     *int64
+		num     = int64(9)
+		ns      sql.NullString
+		scanner recordScanner
+		scanPtr *recordScanner
+	)
+	field := &Field{}
+	field.Scan(int64(7))
+	if err := field.ConvertTo(reflect.ValueOf(&ptr).Elem()); err != nil || ptr == nil || *ptr != 7 {
+		t.Fatalf("pointer: %v, %v", ptr, err)
+	}
+	if err := field.ConvertTo(reflect.ValueOf(&scanPtr).Elem()); err != nil || scanPtr == nil || scanPtr.v != int64(7) {
+		t.Fatalf("pointer to scanner: %v, %v", scanPtr, err)
+	}
+
+	field = &Field{}
+	field.Scan(nil)
+	if err := field.ConvertTo(reflect.ValueOf(&ptr).Elem()); err != nil || ptr != nil {
+		t.Fatalf("NULL pointer: %v, %v", ptr, err)
+	}
+	if err := field.ConvertTo(reflect.ValueOf(&num).Elem()); err != nil || num != 0 {
+		t.Fatalf("NULL value: %v, %v", num, err)
+	}
+	scanner.v = "stale"
+	if err := field.ConvertTo(reflect.ValueOf(&scanner).Elem()); err != nil || scanner.v != nil {
+		t.Fatalf("NULL scanner: %v, %v", scanner.v, err)
+	}
+
+	src := []byte("abc")
+	field = &Field{}
+	field.Scan(src)
+	if err := field.ConvertTo(reflect.ValueOf(&ns).Elem()); err != nil || ns != (sql.NullString{String: "abc", Valid: true}) {
+		t.Fatalf("scanner: %v, %v", ns, err)
+	}
+	var b []byte
+	if err := field.ConvertTo(reflect.ValueOf(&b).Elem()); err != nil || string(b) != "abc" {
+		t.Fatalf("bytes: %s, %v", b, err)
+	}
+	src[0] = 'x'
+	if string(b) != "abc" {
+		t.Fatalf("bytes not copied: %s", b)
+	}
+
+	// ToValue keeps the signature without the error
+	field = &Field{}
+	field.Scan(int64(5))
+	num = 0
+	field.ToValue(reflect.ValueOf(&num).Elem())
+	if num != 5 {
+		t.Fatalf("ToValue: %v", num)
+	}
+}
+
+func TestField_toValue(t *testing.T) {
+	strict := &convertOptions{strict: true}
+	tests := []struct {
+		src     interface{}
+		dst     interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{int64(1), new(int8), int8(1), false},
+		{int64(300), new(int8), nil, true},
+		{[]byte("12"), new(int32), int32(12), false},
+		{[]byte("abc"), new(int64), nil, true},
+		{"1.5", new(int64), nil, true},
+		{"2.0", new(int64), int64(2), false},
+		{int64(-1), new(uint32), nil, true},
+		{"1e400", new(float64), nil, true},
+		{"yes", new(bool), nil, true},
+		{"true", new(bool), true, false},
+		{"2022-01-02", new(struct{ A int }), struct{ A int }{}, false},
+		{[]byte("18446744073709551615"), new(uint64), uint64(18446744073709551615), false},
+		{[]byte("18446744073709551616"), new(uint64), nil, true},
+		{"-1", new(uint64), nil, true},
+		{int64(3), new(string), "3", false},
+		{0.1, new(string), "0.1", false},
+	}
+	for i, tt := range tests {
+		field := &Field{}
+		field.Scan(tt.src)
+		dstVal := reflect.ValueOf(tt.dst).Elem()
+		err := field.toValue(dstVal, strict)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: toValue(%v) error = %v, wantErr %v", i, tt.src, err, tt.wantErr)
+		}
+		if err == nil && !reflect.DeepEqual(dstVal.Interface(), tt.want) {
+			t.Fatalf("%v: toValue(%v) = %v, want %v", i, tt.src, dstVal.Interface(), tt.want)
+		}
+	}
+}
+
+func TestField_toTime(t *testing.T) {
+	opts := &convertOptions{
+		layouts:  []string{time.RFC3339, "2006-01-02"},
+		location: time.FixedZone("UTC+8", 8*3600),
+		epoch:    EpochMillisecond,
+	}
+	tests := []struct {
+		src  interface{}
+		want time.Time
+	}{
+		{int64(1500), time.Unix(1, 5e8)},
+		{"2022-01-02T03:04:05Z", time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{[]byte("2022-01-02"), time.Date(2022, 1, 2, 0, 0, 0, 0, opts.location)},
+	}
+	for i, tt := range tests {
+		field := &Field{}
+		field.Scan(tt.src)
+		got, err := field.toTime(opts)
+		if err != nil || !got.Equal(tt.want) {
+			t.Fatalf("%v: toTime(%v) = %v, %v, want %v", i, tt.src, got, err, tt.want)
+		}
+	}
+
+	// both the integers and the strings default to UTC
+	for _, src := range []interface{}{int64(0), "1970-01-01 00:00:00"} {
+		field := &Field{}
+		field.Scan(src)
+		got, err := field.toTime(defaultConvertOptions)
+		if err != nil || got.Location() != time.UTC || !got.Equal(time.Unix(0, 0)) {
+			t.Fatalf("toTime(%v) = %v, %v, want UTC", src, got, err)
+		}
+	}
+}
+
+func TestSelect_convertError(t *testing.T) {
+	type User struct {
+		Id    int64 `db:"id"`
+		Age   int8  `db:"age"`
+		Score int   `db:"score"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select overflow", fakeResultSet{[]string{"id", "age", "score"}, [][]driver.Value{{int64(1), int64(300), int64(1)}}})
+	server.respond("select invalid", fakeResultSet{[]string{"id", "age", "score"}, [][]driver.Value{{int64(1), int64(3), []byte("abc")}}})
+
+	// strict conversion works without raw scan
+	db.SetRawScan(false)
+	var user User
+	if _, err := db.Select(&user, "select overflow"); err != nil || user.Age != 300-256 {
+		t.Fatalf("not strict: %+v, %v", user, err)
+	}
+
+	db.SetStrictConvert(true)
+	tests := []struct {
+		query  string
+		column string
+		field  string
+		value  interface{}
+	}{
+		{"select overflow", "age", "Age", int64(300)},
+		{"select invalid", "score", "Score", []byte("abc")},
+	}
+	for _, tt := range tests {
+		var users []User
+		_, err := db.Select(&users, tt.query)
+		var convErr *ConvertError
+		if !errors.As(err, &convErr) {
+			t.Fatalf("%v: %v", tt.query, err)
+		}
+		if convErr.Column != tt.column || !strings.HasSuffix(convErr.Field, "User."+tt.field) || !reflect.DeepEqual(convErr.Value, tt.value) || convErr.Err == nil {
+			t.Fatalf("%v: %+v", tt.query, convErr)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// QueryInfo describes a statement passing through the interceptors.
+type QueryInfo struct {
+	// OpType is one of the Op constants.
+	OpType string
+	// Query and Args can be rewritten before calling next, Query is ignored by Stmt.
+	Query string
+	Args  []interface{}
+	// InTx reports whether the statement runs in a Tx or a Stmt prepared by a Tx.
+	InTx bool
+
+	// Duration, RowsAffected, RowsReturned and Err are set after next returns.
+	Duration     time.Duration
+	RowsAffected int64
+	// RowsReturned is the number of the rows mapped into the destinations of the selects.
+	RowsReturned int64
+	Err          error
+
+	db   *DB
+	tx   *Tx
+	cols *argColumns
+	// callers are the program counters of the stack calling sqlw, captured before the
+	// interceptors for the slow query log.
+	callers []uintptr
+	// stmt, driverQuery and driverArgs are set before calling the driver.
+	stmt        bool
+	driverQuery string
+	driverArgs  []interface{}
+}
+
+// Handler executes the statement described by info.
+type Handler func(ctx context.Context, info *QueryInfo) (Result, error)
+
+// Interceptor wraps the statements, it can call next with a modified info, or return
+// without calling next to short-circuit.
+type Interceptor func(ctx context.Context, info *QueryInfo, next Handler) (Result, error)
+
+// Use appends the interceptors, the first one is the outermost.
+func (db *DB) Use(interceptors ...Interceptor) {
+	chain := make([]Interceptor, 0, len(db.interceptors)+len(interceptors))
+	chain = append(chain, db.interceptors...)
+	db.interceptors = append(chain, interceptors...)
+}
+
+// txSelector carries the Tx to the interceptors.
+type txSelector struct {
+	*sql.Tx
+	tx *Tx
+}
+
+func (tx *Tx) selector() Selector {
+	return &txSelector{Tx: tx.Tx, tx: tx}
+}
+
+func newQueryInfo(selector Selector, stmt *Stmt, opType, query string, args []interface{}) *QueryInfo {
+	info := &QueryInfo{OpType: opType, Query: query, Args: args, stmt: stmt != nil}
+	if stmt != nil {
+		info.tx = stmt.tx
+	} else if s, ok := selector.(*txSelector); ok {
+		info.tx = s.tx
+	}
+	info.InTx = info.tx != nil
+	return info
+}
+
+// invoke runs h through the interceptors, the returned Result is never nil.
+func (db *DB) invoke(ctx context.Context, info *QueryInfo, h Handler) (Result, error) {
+	info.db = db
+	if db.slowQueryLog != nil {
+		info.callers = callers()
+	}
+	if info.tx != nil {
+		ctx = info.tx.spanContext(ctx)
+	}
+	next := func(ctx context.Context, info *QueryInfo) (Result, error) {
+		begin := time.Now()
+		var result Result
+		err := db.driverQuery(ctx, info)
+		if err == nil {
+			result, err = h(ctx, info)
+		}
+		info.Duration = time.Since(begin)
+		info.Err = err
+		if result != nil && result.Result != nil {
+			if n, err := result.Result.RowsAffected(); err == nil {
+				info.RowsAffected = n
+			}
+		}
+		db.observe(ctx, info)
+		return result, err
+	}
+	for i := len(db.interceptors) - 1; i >= 0; i-- {
+		interceptor, h := db.interceptors[i], next
+		next = func(ctx context.Context, info *QueryInfo) (Result, error) {
+			return interceptor(ctx, info, h)
+		}
+	}
+	result, err := db.trace(ctx, info, next)
+	if result == nil {
+		result = &sqlResult{db: db, query: info.Query, args: info.Args}
+	}
+	if result.cols == nil {
+		result.cols = info.cols
+	}
+	return result, err
+}
+
+// mapRows maps the rows into dst by f and adds the number of the mapped rows to RowsReturned,
+// the existing elements are excluded only in append mode since dst is truncated otherwise.
+func (info *QueryInfo) mapRows(dst interface{}, opts *queryOptions, f func() (bool, error)) (bool, error) {
+	var before int64
+	_, isCollection := dstRows(dst)
+	if isCollection && opts.appendRows {
+		before, _ = dstRows(dst)
+	}
+	notFound, err := f()
+	if isCollection {
+		after, _ := dstRows(dst)
+		if after > before {
+			info.RowsReturned += after - before
+		}
+	} else if !notFound && err == nil {
+		info.RowsReturned++
+	}
+	return notFound, err
+}
+
+// dstRows returns the number of the elements of slice and map destinations, the slice
+// values of *map[K][]T are counted too.
+func dstRows(dst interface{}) (int64, bool) {
+	v := reflect.Indirect(reflect.ValueOf(dst))
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return 0, false
+		}
+		return int64(v.Len()), true
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Slice {
+			return int64(v.Len()), true
+		}
+		var n int64
+		iter := v.MapRange()
+		for iter.Next() {
+			n += int64(iter.Value().Len())
+		}
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// driverQuery sets the query and the args passed to the driver, the args are inlined in
+// InterpolateParams mode and the sql comment is appended. The statements of Stmt are
+// already prepared and passed through, Prepare gets only the comment of the static tags so
+// that the tags of the ctx of Prepare aren't baked into the statement, and the args of Call
+// are never inlined since the sql.Out args must reach the driver.
+func (db *DB) driverQuery(ctx context.Context, info *QueryInfo) error {
+	info.driverQuery, info.driverArgs = info.Query, info.Args
+	if info.stmt {
+		return nil
+	}
+	switch info.OpType {
+	case opTypPrepare:
+		info.driverQuery = appendComment(info.driverQuery, db.staticComment(info.Query))
+		return nil
+	case opTypBegin, opTypCommit, opTypRollback:
+		return nil
+	}
+	if db.interpolateParams && len(info.Args) > 0 && info.OpType != opTypCall {
+		if err := db.checkInterpolate(); err != nil {
+			return fmt.Errorf("[sqlw %v] %v: %v", info.OpType, err, info.Query)
+		}
+		query, err := db.interpolate(info.Query, info.Args, true)
+		if err != nil {
+			return fmt.Errorf("[sqlw %v] %v: %v", info.OpType, err, info.Query)
+		}
+		info.driverQuery, info.driverArgs = query, nil
+	}
+	info.driverQuery = appendComment(info.driverQuery, db.comment(ctx, info.Query))
+	return nil
+}
+
+// observe reports the executed statement to the built-in collectors.
+func (db *DB) observe(ctx context.Context, info *QueryInfo) {
+	if db.statsCollector != nil {
+		db.statsCollector.record(info)
+	}
+	db.logSlowQuery(info)
+	db.log(ctx, info)
+}
+
+func execContext(db *DB, ctx context.Context, selector Selector, stmt *Stmt, opType, query string, args []interface{}, cols *argColumns) (Result, error) {
+	info := newQueryInfo(selector, stmt, opType, query, args)
+	info.cols = cols
+	return db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		var result sql.Result
+		var err error
+		if stmt != nil {
+			result, err = stmt.Stmt.ExecContext(ctx, info.driverArgs...)
+		} else {
+			result, err = selector.ExecContext(ctx, info.driverQuery, info.driverArgs...)
+		}
+		return newResult(db, result, info.Query, info.Args, false), err
+	})
+}
+
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+func prepareContext(db *DB, ctx context.Context, selector Selector, p preparer, query string) (*Stmt, error) {
+	var stmt *sql.Stmt
+	info := newQueryInfo(selector, nil, opTypPrepare, query, nil)
+	_, err := db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		var err error
+		stmt, err = p.PrepareContext(ctx, info.driverQuery)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return nil, fmt.Errorf("[sqlw %v] statement not prepared by the interceptors: %v", opTypPrepare, query)
+	}
+	s := NewStmt(db, stmt, info.Query)
+	s.tx = info.tx
+	return s, nil
+}
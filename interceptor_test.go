@@ -0,0 +1,132 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		db, _ := newFakeDB(t, "fakemysql")
+		var calls []string
+		for _, name := range []string{"a", "b"} {
+			name := name
+			db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+				calls = append(calls, name+" before")
+				result, err := next(ctx, info)
+				calls = append(calls, name+" after")
+				return result, err
+			})
+		}
+		if _, err := db.Exec("delete from users"); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(calls, []string{"a before", "b before", "b after", "a after"}) {
+			t.Fatalf("calls: %v", calls)
+		}
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		errDenied := errors.New("denied")
+		db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+			if info.OpType == OpDelete {
+				return nil, errDenied
+			}
+			return next(ctx, info)
+		})
+		if _, err := db.Exec("delete from users"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Delete("delete from users where id=?", 1); err != errDenied {
+			t.Fatalf("err: %v", err)
+		}
+		if queries := server.queries(); !reflect.DeepEqual(queries, []string{"delete from users"}) {
+			t.Fatalf("queries: %q", queries)
+		}
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		type User struct {
+			Id int64 `db:"id"`
+		}
+		db, server := newFakeDB(t, "fakemysql")
+		server.respond("select id from users_v2 where id=?", fakeResultSet{[]string{"id"}, [][]driver.Value{{int64(2)}}})
+		var infos []QueryInfo
+		db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+			if info.OpType == OpSelect {
+				info.Query = strings.Replace(info.Query, "users", "users_v2", 1)
+				info.Args = []interface{}{2}
+			}
+			result, err := next(ctx, info)
+			infos = append(infos, *info)
+			return result, err
+		})
+		var users []User
+		result, err := db.Select(&users, "select id from users where id=?", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(users, []User{{2}}) || result.Sql() != "[select id from users_v2 where id=?], [2]" {
+			t.Fatalf("users: %v, sql: %v", users, result.Sql())
+		}
+		if last := server.last(); !reflect.DeepEqual(last.args, []driver.Value{2}) {
+			t.Fatalf("args: %v", last.args)
+		}
+		if len(infos) != 1 || infos[0].RowsReturned != 1 || infos[0].Err != nil {
+			t.Fatalf("infos: %+v", infos)
+		}
+	})
+
+	t.Run("tx", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		var ops []string
+		db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+			ops = append(ops, info.OpType+" "+info.Query)
+			if !info.InTx && info.OpType != OpBegin {
+				t.Errorf("%v should be in tx", info.OpType)
+			}
+			return next(ctx, info)
+		})
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tx.Exec("delete from users"); err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ops, []string{OpBegin + " " + OpBegin, OpExec + " delete from users", OpCommit + " " + OpCommit}) {
+			t.Fatalf("ops: %q", ops)
+		}
+		if queries := server.queries(); !reflect.DeepEqual(queries, []string{"begin", "delete from users", "commit"}) {
+			t.Fatalf("queries: %q", queries)
+		}
+	})
+
+	t.Run("tx short-circuit", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+			if info.OpType == OpBegin {
+				return nil, nil
+			}
+			return next(ctx, info)
+		})
+		if _, err := db.Begin(); err == nil {
+			t.Fatalf("Begin should fail without calling next")
+		}
+		if queries := server.queries(); len(queries) != 0 {
+			t.Fatalf("queries: %q", queries)
+		}
+	})
+}
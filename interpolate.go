@@ -0,0 +1,363 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SetInterpolateParams makes the statements with args sent to the driver as plain sql with the
+// args inlined, which saves the round trips of the server-side prepared statements. Only nil,
+// bool, the integers, the floats, the valid UTF-8 strings, []byte, time.Time, the pointers to
+// them and the driver.Valuer returning them are allowed, other args fail the statements. The
+// time.Time args are formatted in TimeLocation. The statements of Stmt and Call are not
+// interpolated. Only the MySQL and PostgreSQL drivers are supported, the statements of the
+// other drivers fail. For MySQL, the charset of the connection must be declared by SetCharset,
+// and SetNoBackslashEscapes must be set too with NO_BACKSLASH_ESCAPES in sql_mode.
+func (db *DB) SetInterpolateParams(interpolate bool) {
+	db.interpolateParams = interpolate
+}
+
+func (db *DB) InterpolateParams() bool {
+	return db.interpolateParams
+}
+
+// SetNoBackslashEscapes makes the MySQL string literals interpolated with the quotes doubled
+// instead of the backslash escapes, it must match NO_BACKSLASH_ESCAPES in sql_mode of the server.
+func (db *DB) SetNoBackslashEscapes(noBackslashEscapes bool) {
+	db.noBackslashEscapes = noBackslashEscapes
+}
+
+func (db *DB) NoBackslashEscapes() bool {
+	return db.noBackslashEscapes
+}
+
+// SetCharset declares the charset of the connections, such as the charset param of the MySQL
+// dsn. The MySQL statements are interpolated only with utf8, utf8mb3, utf8mb4 or latin1, since
+// the backslash escapes are unsafe with the multi-byte charsets like GBK, Big5 and SJIS, whose
+// characters may end with the byte of backslash.
+func (db *DB) SetCharset(charset string) {
+	db.charset = charset
+}
+
+func (db *DB) Charset() string {
+	return db.charset
+}
+
+const (
+	dialectMysql    = "mysql"
+	dialectPostgres = "postgres"
+)
+
+// dialectOf returns the dialect of the driver supported by the interpolation, or "" for the others.
+func dialectOf(driverName string) string {
+	switch {
+	case strings.Contains(driverName, "mysql"):
+		return dialectMysql
+	case strings.Contains(driverName, "postgres") || strings.Contains(driverName, "pgx") || driverName == "pq":
+		return dialectPostgres
+	}
+	return ""
+}
+
+func (db *DB) isPostgres() bool {
+	return db.dialect == dialectPostgres
+}
+
+// checkInterpolate returns the error if the statements can't be interpolated safely.
+func (db *DB) checkInterpolate() error {
+	switch db.dialect {
+	case dialectPostgres:
+		return nil
+	case dialectMysql:
+		switch strings.ToLower(db.charset) {
+		case "utf8", "utf8mb3", "utf8mb4", "latin1":
+			return nil
+		case "":
+			return fmt.Errorf("the charset must be declared by SetCharset to interpolate params")
+		}
+		return fmt.Errorf("can't interpolate params with charset %v", db.charset)
+	}
+	return fmt.Errorf("can't interpolate params for the dialect of the driver")
+}
+
+// interpolate inlines args into the "?" or "$N" placeholders of query, skipping the string
+// literals, quoted identifiers and comments of the dialect. In strict mode, the args of the types out of
+// the whitelist and the mismatched placeholders return errors, otherwise the unsupported
+// args are formatted as strings and the placeholders without args are kept.
+func (db *DB) interpolate(query string, args []interface{}, strict bool) (string, error) {
+	dollar := db.placeholder != "?"
+	var b strings.Builder
+	b.Grow(len(query) + len(args)*8)
+	argIdx := 0
+	used := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		if end, _ := db.skipToken(query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		switch {
+		case !dollar && c == '?':
+			idx := argIdx
+			argIdx++
+			if idx >= len(args) {
+				if strict {
+					return "", fmt.Errorf("missing arg for placeholder %v", idx+1)
+				}
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			if err := db.writeLiteral(&b, args[idx], strict); err != nil {
+				return "", err
+			}
+			used++
+			i++
+			continue
+		case dollar && c == '$' && i+1 < len(query) && isDigit(query[i+1]) && (i == 0 || !isIdentChar(query[i-1])):
+			end := i + 1
+			for end < len(query) && isDigit(query[end]) {
+				end++
+			}
+			n, _ := strconv.Atoi(query[i+1 : end])
+			if n < 1 || n > len(args) {
+				if strict {
+					return "", fmt.Errorf("missing arg for placeholder %v", query[i:end])
+				}
+				b.WriteString(query[i:end])
+				i = end
+				continue
+			}
+			if err := db.writeLiteral(&b, args[n-1], strict); err != nil {
+				return "", err
+			}
+			if n > used {
+				used = n
+			}
+			i = end
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	if strict && used != len(args) {
+		return "", fmt.Errorf("args num %v doesn't match placeholders num %v", len(args), used)
+	}
+	return b.String(), nil
+}
+
+// skipToken returns the index after the string literal, quoted identifier or comment starting
+// at i, and whether it's a comment, or i if there's none. Besides the standard ones, MySQL has
+// the backslash escapes in the strings unless NoBackslashEscapes and the "#" comments, and PostgreSQL has the backslash
+// escapes in the E'...' strings and the $tag$...$tag$ strings.
+func (db *DB) skipToken(query string, i int) (int, bool) {
+	postgres := db.isPostgres()
+	mysqlEscape := db.isMysql && !db.noBackslashEscapes
+	switch c := query[i]; {
+	case c == '\'':
+		escape := mysqlEscape || (postgres && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i == 1 || !isIdentChar(query[i-2])))
+		return skipQuoted(query, i, escape), false
+	case c == '"' || c == '`':
+		return skipQuoted(query, i, mysqlEscape && c == '"'), false
+	case c == '#' && db.isMysql, c == '-' && strings.HasPrefix(query[i:], "--"):
+		end := strings.IndexByte(query[i:], '\n')
+		if end < 0 {
+			return len(query), true
+		}
+		return i + end, true
+	case c == '/' && strings.HasPrefix(query[i:], "/*"):
+		end := strings.Index(query[i+2:], "*/")
+		if end < 0 {
+			return len(query), true
+		}
+		return i + end + 4, true
+	case c == '$' && postgres && (i == 0 || !isIdentChar(query[i-1])):
+		// $$ or $tag$, the tag doesn't start with a digit so that $1 is a placeholder
+		j := i + 1
+		if j < len(query) && !isDigit(query[j]) {
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+		}
+		if j >= len(query) || query[j] != '$' {
+			return i, false
+		}
+		tag := query[i : j+1]
+		end := strings.Index(query[j+1:], tag)
+		if end < 0 {
+			return len(query), false
+		}
+		return j + 1 + end + len(tag), false
+	}
+	return i, false
+}
+
+// skipQuoted returns the index after the quoted string or identifier starting at i,
+// the backslash escapes work if backslash is true.
+func skipQuoted(query string, i int, backslash bool) int {
+	quote := query[i]
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// writeLiteral writes the dialect-correct literal of v.
+func (db *DB) writeLiteral(b *strings.Builder, v interface{}, strict bool) error {
+	if valuer, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		v = value
+	}
+
+	switch x := v.(type) {
+	case nil:
+		b.WriteString("NULL")
+		return nil
+	case time.Time:
+		x = x.In(db.TimeLocation())
+		b.WriteByte('\'')
+		if db.isMysql {
+			b.WriteString(x.Format("2006-01-02 15:04:05.999999"))
+		} else {
+			b.WriteString(x.Format("2006-01-02 15:04:05.999999Z07:00"))
+		}
+		b.WriteByte('\'')
+		return nil
+	case []byte:
+		switch {
+		case x == nil:
+			b.WriteString("NULL")
+		case db.isPostgres():
+			b.WriteString(`E'\\x`)
+			b.WriteString(hex.EncodeToString(x))
+			b.WriteByte('\'')
+		default:
+			b.WriteString("X'")
+			b.WriteString(hex.EncodeToString(x))
+			b.WriteByte('\'')
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return db.writeLiteral(b, rv.Bytes(), strict)
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		return db.writeLiteral(b, rv.Elem().Interface(), strict)
+	case reflect.Bool:
+		if rv.Bool() {
+			b.WriteString("TRUE")
+		} else {
+			b.WriteString("FALSE")
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(rv.Int(), 10))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.FormatUint(rv.Uint(), 10))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			if strict {
+				return fmt.Errorf("can't interpolate float value %v", f)
+			}
+			db.writeString(b, strconv.FormatFloat(f, 'g', -1, 64))
+			return nil
+		}
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()))
+		return nil
+	case reflect.String:
+		if strict && !utf8.ValidString(rv.String()) {
+			return fmt.Errorf("can't interpolate invalid UTF-8 string %q", rv.String())
+		}
+		db.writeString(b, rv.String())
+		return nil
+	default:
+		if strict {
+			return fmt.Errorf("can't interpolate arg of type %T", v)
+		}
+		db.writeString(b, fmt.Sprintf("%v", v))
+		return nil
+	}
+}
+
+// writeString writes the quoted string. MySQL escapes the special characters by backslash
+// unless NoBackslashEscapes, PostgreSQL writes the E'...' string escaping the backslashes and
+// the quotes by backslash, which doesn't depend on standard_conforming_strings, and the others
+// double the quotes.
+func (db *DB) writeString(b *strings.Builder, s string) {
+	switch {
+	case db.isPostgres():
+		b.WriteString("E'")
+		for i := 0; i < len(s); i++ {
+			if c := s[i]; c == '\\' || c == '\'' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(s[i])
+		}
+		b.WriteByte('\'')
+		return
+	case !db.isMysql || db.noBackslashEscapes:
+		b.WriteByte('\'')
+		b.WriteString(strings.ReplaceAll(s, "'", "''"))
+		b.WriteByte('\'')
+		return
+	}
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\x1a':
+			b.WriteString(`\Z`)
+		case '\\', '\'', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	tm := time.Date(2022, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	var nilPtr *int
+	mysql := &DB{placeholder: "?", isMysql: true, dialect: dialectMysql}
+	got, err := mysql.interpolate("select * from t where a=? and b='?' and c=? and d=? and e=? and f=? and g=? -- ?",
+		[]interface{}{"it's\n\\", []byte{0xde, 0xad}, tm, true, nilPtr, 1.5}, false)
+	want := `select * from t where a='it\'s\n\\' and b='?' and c=X'dead' and d='2022-01-02 03:04:05.6' and e=TRUE and f=NULL and g=1.5 -- ?`
+	if err != nil || got != want {
+		t.Fatalf("mysql interpolate = %q, %v, want %q", got, err, want)
+	}
+
+	pg := &DB{placeholder: "$", dialect: dialectPostgres}
+	got, err = pg.interpolate(`select * from "t?" where a=$2 and b=$1 and c=$2 and d=$3`,
+		[]interface{}{int8(-3), "it's\\", []byte{0x01}}, false)
+	want = `select * from "t?" where a=E'it\'s\\' and b=-3 and c=E'it\'s\\' and d=E'\\x01'`
+	if err != nil || got != want {
+		t.Fatalf("postgres interpolate = %q, %v, want %q", got, err, want)
+	}
+
+	if _, err = mysql.interpolate("select ?", []interface{}{struct{}{}}, true); err == nil {
+		t.Fatalf("strict interpolate with unsupported type should fail")
+	}
+	if _, err = mysql.interpolate("select ?, ?", []interface{}{1}, true); err == nil {
+		t.Fatalf("strict interpolate with missing arg should fail")
+	}
+}
+
+func TestInterpolate_tokens(t *testing.T) {
+	mysql := &DB{placeholder: "?", isMysql: true, dialect: dialectMysql}
+	pg := &DB{placeholder: "$", dialect: dialectPostgres}
+	tests := []struct {
+		db    *DB
+		query string
+		want  string
+	}{
+		{mysql, "select ? # ?'\n, ?", "select 1 # ?'\n, 2"},
+		{mysql, `select 'a\'?', "b\"?", ?, ?`, `select 'a\'?', "b\"?", 1, 2`},
+		{mysql, "select ? -- ?\n, ? /* ? */", "select 1 -- ?\n, 2 /* ? */"},
+		{pg, `select E'a\'$1', e'\\', $1, $2`, `select E'a\'$1', e'\\', 1, 2`},
+		{pg, `select 'a\', $1, $2`, `select 'a\', 1, 2`},
+		{pg, "select $$ it's $1 $$, $1, $fn$ $2 $$ $fn$, $2", "select $$ it's $1 $$, 1, $fn$ $2 $$ $fn$, 2"},
+		{pg, "select $1 # $2", "select 1 # 2"},
+		{pg, "select name$1 from t where a=$1 and b=$2", "select name$1 from t where a=1 and b=2"},
+	}
+	for _, tt := range tests {
+		got, err := tt.db.interpolate(tt.query, []interface{}{1, 2}, true)
+		if err != nil || got != tt.want {
+			t.Fatalf("interpolate(%q) = %q, %v, want %q", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestDriverQuery_hostile(t *testing.T) {
+	hostiles := []string{
+		`'; drop table users; --`,
+		`\'; drop table users; --`,
+		`\`,
+		`\\'`,
+		`' or 1=1 #`,
+		`*/ or 1=1 /*`,
+		`$1 $$ $tag$`,
+		"\n\r\x1a\"",
+	}
+	mysql, _ := newFakeDB(t, "fakemysql")
+	noBackslash, _ := newFakeDB(t, "fakemysql")
+	noBackslash.SetNoBackslashEscapes(true)
+	pg, _ := newFakeDB(t, "fakepostgres")
+	for _, db := range []*DB{mysql, noBackslash, pg} {
+		db.SetInterpolateParams(true)
+		db.SetCharset("utf8mb4")
+		query := "select * from users where name=? and id=?"
+		if db.isPostgres() {
+			query = "select * from users where name=$1 and id=$2"
+		}
+		prefix := query[:strings.Index(query, "name=")+len("name=")]
+		for _, hostile := range hostiles {
+			info := &QueryInfo{OpType: OpSelect, Query: query, Args: []interface{}{hostile, 1}}
+			if err := db.driverQuery(context.Background(), info); err != nil {
+				t.Fatal(err)
+			}
+			if len(info.driverArgs) != 0 || !strings.HasPrefix(info.driverQuery, prefix) {
+				t.Fatalf("driverQuery(%q) = %q, %v", hostile, info.driverQuery, info.driverArgs)
+			}
+			// the literal must end right before the next condition
+			start := len(prefix)
+			if db.isPostgres() {
+				start++ // E
+			}
+			end, _ := db.skipToken(info.driverQuery, start)
+			if end == start || info.driverQuery[end:] != " and id=1" {
+				t.Fatalf("driverQuery(%q) = %q, literal ends at %v", hostile, info.driverQuery, end)
+			}
+		}
+
+		info := &QueryInfo{OpType: OpSelect, Query: query, Args: []interface{}{"\xbf\x27 or 1=1", 1}}
+		if err := db.driverQuery(context.Background(), info); err == nil {
+			t.Fatalf("invalid UTF-8 should fail: %q", info.driverQuery)
+		}
+	}
+
+	if got := mustInterpolate(t, noBackslash, "select ?", `a\'b`); got != `select 'a\''b'` {
+		t.Fatalf("no backslash escapes: %q", got)
+	}
+}
+
+func mustInterpolate(t *testing.T, db *DB, query string, args ...interface{}) string {
+	got, err := db.interpolate(query, args, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestInterpolate_time(t *testing.T) {
+	tm := time.Date(2022, 1, 2, 11, 4, 5, 0, time.FixedZone("UTC+8", 8*3600))
+	mysql := &DB{placeholder: "?", isMysql: true, dialect: dialectMysql}
+	if got := mustInterpolate(t, mysql, "select ?", tm); got != "select '2022-01-02 03:04:05'" {
+		t.Fatalf("utc: %q", got)
+	}
+	mysql.SetTimeLocation(time.FixedZone("UTC-1", -3600))
+	if got := mustInterpolate(t, mysql, "select ?", tm); got != "select '2022-01-02 02:04:05'" {
+		t.Fatalf("location: %q", got)
+	}
+	pg := &DB{placeholder: "$", dialect: dialectPostgres}
+	if got := mustInterpolate(t, pg, "select $1", tm); got != "select '2022-01-02 03:04:05Z'" {
+		t.Fatalf("postgres: %q", got)
+	}
+}
+
+func TestSetInterpolateParams(t *testing.T) {
+	type User struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	db.SetInterpolateParams(true)
+	db.SetCharset("utf8mb4")
+	check := func(want string) {
+		t.Helper()
+		if last := server.last(); last.query != want || len(last.args) != 0 {
+			t.Fatalf("got %q, %v, want %q", last.query, last.args, want)
+		}
+	}
+
+	if _, err := db.Exec("delete from users where name=?", "it's"); err != nil {
+		t.Fatal(err)
+	}
+	check(`delete from users where name='it\'s'`)
+	if _, err := db.Insert("insert into users", &User{1, "a"}); err != nil {
+		t.Fatal(err)
+	}
+	check("insert into users(id,name) values(1,'a')")
+	if _, err := db.Update("update users set name=? where id=?", &User{Name: "b"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	check("update users set name='b' where id=1")
+
+	type In struct {
+		Id int64 `db:"id"`
+	}
+	if _, err := db.Call("p", &In{1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if last := server.last(); last.query != "call p(?)" || !reflect.DeepEqual(last.args, []driver.Value{int64(1)}) {
+		t.Fatalf("call: %q, %v", last.query, last.args)
+	}
+}
+
+func TestSetInterpolateParams_unsupported(t *testing.T) {
+	db, server := newFakeDB(t, "fakemysql")
+	db.SetInterpolateParams(true)
+	for _, charset := range []string{"", "gbk", "big5", "sjis"} {
+		db.SetCharset(charset)
+		if _, err := db.Exec("delete from users where name=?", "a"); err == nil {
+			t.Fatalf("charset %q should fail", charset)
+		}
+	}
+	for _, charset := range []string{"utf8", "UTF8MB4", "latin1"} {
+		db.SetCharset(charset)
+		if _, err := db.Exec("delete from users where name=?", "a"); err != nil {
+			t.Fatalf("charset %q: %v", charset, err)
+		}
+	}
+	if queries := server.queries(); len(queries) != 3 {
+		t.Fatalf("queries: %q", queries)
+	}
+
+	pg, _ := newFakeDB(t, "fakepostgres")
+	pg.SetInterpolateParams(true)
+	if _, err := pg.Exec("delete from users where name=$1", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, driverName := range []string{"sqlite3", "sqlserver"} {
+		other := Wrap(db.DB, driverName, "db")
+		other.SetInterpolateParams(true)
+		if _, err := other.Exec("delete from users where name=$1", "a"); err == nil {
+			t.Fatalf("%v should fail", driverName)
+		}
+		if _, err := other.Exec("delete from users"); err != nil {
+			t.Fatalf("%v without args: %v", driverName, err)
+		}
+	}
+}
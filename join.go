@@ -0,0 +1,418 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// joinTag marks a slice field to be filled from the joined columns with the
+// tag value as the column prefix, e.g.
+//
+//	type User struct {
+//		Id     int64   `db:"id,pk"`
+//		Orders []Order `join:"o"` // filled by columns named "o.xxx" or "o__xxx"
+//	}
+const joinTag = "join"
+
+type joinChild struct {
+	prefix    string
+	fieldIdx  int
+	elemTyp   reflect.Type
+	isPtr     bool
+	pkColumn  int
+	columnMap map[int]int // column index -> child field index
+}
+
+type joinMapping struct {
+	pkColumn  int
+	columnMap map[int]int // column index -> parent field index
+	children  []*joinChild
+}
+
+// splitColumn splits "o.id" or "o__id" into prefix "o" and name "id".
+func splitColumn(column string) (string, string) {
+	if idx := strings.LastIndex(column, "."); idx > 0 {
+		return column[:idx], column[idx+1:]
+	}
+	if idx := strings.Index(column, "__"); idx > 0 {
+		return column[:idx], column[idx+2:]
+	}
+	return "", column
+}
+
+// joinColumn returns the join child and the name of the column prefixed by a declared join
+// prefix, or nil and the full column otherwise, so that "created__at" stays a parent column
+// unless "created" is a join prefix.
+func joinColumn(db *DB, column string, prefixes map[string]*joinChild) (*joinChild, string) {
+	prefix, name := splitColumn(column)
+	if prefix != "" {
+		if child, ok := prefixes[db.columnKey(prefix)]; ok {
+			return child, name
+		}
+	}
+	return nil, column
+}
+
+type joinFieldsKey struct {
+	typ reflect.Type
+}
+
+func hasJoinFields(db *DB, typ reflect.Type) bool {
+	key := joinFieldsKey{typ}
+	if stored, ok := db.mapping.Load(key); ok {
+		return stored.(bool)
+	}
+	has := false
+	for i := 0; i < typ.NumField(); i++ {
+		if _, ok := typ.Field(i).Tag.Lookup(joinTag); ok {
+			has = true
+			break
+		}
+	}
+	db.mapping.Store(key, has)
+	return has
+}
+
+// segmentColumns splits the indexes of the unprefixed columns into segments by position, a
+// new segment starts when a name repeats in the current one, e.g. "select u.*, o.*" returns the columns
+// of u and o as two segments.
+func segmentColumns(db *DB, columns []string, idxs []int) [][]int {
+	var segments [][]int
+	var seen map[string]bool
+	for _, i := range idxs {
+		name := db.columnKey(columns[i])
+		if seen == nil || seen[name] {
+			segments = append(segments, nil)
+			seen = map[string]bool{}
+		}
+		seen[name] = true
+		segments[len(segments)-1] = append(segments[len(segments)-1], i)
+	}
+	return segments
+}
+
+// pkFieldIdx returns the primary key field: the one tagged with the "pk" option,
+// or the one named "id" if no field of the struct has the option.
+func pkFieldIdx(db *DB, typ reflect.Type) (int, string) {
+	idx, name := -1, ""
+	for i := 0; i < typ.NumField(); i++ {
+		strField := typ.Field(i)
+		fieldName := db.parseFieldName(&strField)
+		if fieldName == "" {
+			continue
+		}
+		if db.parseFieldOptions(&strField).Contains("pk") {
+			return i, fieldName
+		}
+		if idx < 0 && db.columnKey(fieldName) == "id" {
+			idx, name = i, fieldName
+		}
+	}
+	return idx, name
+}
+
+func getJoinMapping(db *DB, columns []string, typ reflect.Type, key string) (*joinMapping, error) {
+	if stored, ok := db.mapping.Load(key); ok {
+		return stored.(*joinMapping), nil
+	}
+
+	m := &joinMapping{pkColumn: -1, columnMap: map[int]int{}}
+	prefixes := map[string]*joinChild{}
+	for i := 0; i < typ.NumField(); i++ {
+		strField := typ.Field(i)
+		prefix, ok := strField.Tag.Lookup(joinTag)
+		if !ok {
+			continue
+		}
+		elemTyp := strField.Type
+		if elemTyp.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("[sqlw %v] invalid join field type %v.%v: %v", opTypSelect, typ, strField.Name, elemTyp)
+		}
+		child := &joinChild{prefix: prefix, fieldIdx: i, elemTyp: elemTyp.Elem(), pkColumn: -1, columnMap: map[int]int{}}
+		if child.elemTyp.Kind() == reflect.Ptr {
+			child.isPtr = true
+			child.elemTyp = child.elemTyp.Elem()
+		}
+		if child.elemTyp.Kind() != reflect.Struct || isScalarType(db, child.elemTyp) {
+			return nil, fmt.Errorf("[sqlw %v] invalid join field type %v.%v: %v", opTypSelect, typ, strField.Name, elemTyp)
+		}
+		m.children = append(m.children, child)
+		prefixes[db.columnKey(prefix)] = child
+	}
+
+	fieldIdxMap := func(typ reflect.Type) map[string]int {
+		idxMap := map[string]int{}
+		for i := 0; i < typ.NumField(); i++ {
+			strField := typ.Field(i)
+			if fieldName := db.parseFieldName(&strField); fieldName != "" {
+				idxMap[db.columnKey(fieldName)] = i
+			}
+		}
+		return idxMap
+	}
+
+	// mapColumns maps the columns of idxs to the fields of typ, returns the pk column
+	mapColumns := func(typ reflect.Type, idxs []int, columnMap map[int]int) int {
+		fields := fieldIdxMap(typ)
+		pkIdx, _ := pkFieldIdx(db, typ)
+		pkColumn := -1
+		for _, i := range idxs {
+			_, name := joinColumn(db, columns[i], prefixes)
+			if fieldIdx, ok := fields[db.columnKey(name)]; ok {
+				columnMap[i] = fieldIdx
+				if fieldIdx == pkIdx && pkColumn < 0 {
+					pkColumn = i
+				}
+			}
+		}
+		return pkColumn
+	}
+
+	var unprefixed []int
+	prefixed := map[*joinChild][]int{}
+	for i, column := range columns {
+		if child, _ := joinColumn(db, column, prefixes); child != nil {
+			prefixed[child] = append(prefixed[child], i)
+		} else {
+			unprefixed = append(unprefixed, i)
+		}
+	}
+
+	// the children without prefixed columns take the segments of the duplicate unprefixed
+	// columns by position in the order of the join fields, e.g. "select u.*, o.*"
+	segments := segmentColumns(db, columns, unprefixed)
+	if len(segments) > 1 {
+		var positional []*joinChild
+		for _, child := range m.children {
+			if len(prefixed[child]) == 0 {
+				positional = append(positional, child)
+			}
+		}
+		if len(segments)-1 > len(positional) {
+			return nil, fmt.Errorf("[sqlw %v] duplicate columns of %v can't be mapped by position, alias them with the join prefixes: %v", opTypSelect, typ, columns)
+		}
+		for i, segment := range segments[1:] {
+			prefixed[positional[i]] = segment
+		}
+		unprefixed = segments[0]
+	}
+
+	for _, child := range m.children {
+		child.pkColumn = mapColumns(child.elemTyp, prefixed[child], child.columnMap)
+	}
+	m.pkColumn = mapColumns(typ, unprefixed, m.columnMap)
+	if m.pkColumn < 0 {
+		return nil, fmt.Errorf("[sqlw %v] primary key column of %v not found in columns: %v", opTypSelect, typ, columns)
+	}
+
+	db.mapping.Store(key, m)
+	return m, nil
+}
+
+// mismatch returns the columns mapped to neither the parent nor the children, and the fields
+// of them without columns, the fields of the children are prefixed with the join fields.
+func (m *joinMapping) mismatch(db *DB, columns []string, typ reflect.Type) *columnMismatch {
+	mismatch := &columnMismatch{}
+	mapped := map[int]bool{}
+	for _, fieldIdx := range m.columnMap {
+		mapped[fieldIdx] = true
+	}
+	mismatch.missing = missingFields(db, typ, mapped, "")
+	for _, child := range m.children {
+		childMapped := map[int]bool{}
+		for _, fieldIdx := range child.columnMap {
+			childMapped[fieldIdx] = true
+		}
+		prefix := typ.Field(child.fieldIdx).Name + "."
+		mismatch.missing = append(mismatch.missing, missingFields(db, child.elemTyp, childMapped, prefix)...)
+	}
+	for i, column := range columns {
+		if _, ok := m.columnMap[i]; ok {
+			continue
+		}
+		found := false
+		for _, child := range m.children {
+			if _, ok := child.columnMap[i]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			mismatch.unmapped = append(mismatch.unmapped, column)
+		}
+	}
+	return mismatch
+}
+
+// rowsToJoined groups the joined rows by the parent primary key and appends the prefixed
+// columns to the join fields. Rows are always scanned into Fields since the joined columns
+// may be NULL.
+func rowsToJoined(db *DB, rows *sql.Rows, dst interface{}, key string, opts *queryOptions) (bool, error) {
+	dstTyp := reflect.TypeOf(dst)
+	isSlice := isStructSlicePtr(db, dstTyp)
+	if !isSlice && !isStructPtr(dstTyp) {
+		return false, fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, dstTyp)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+
+	dstValue := reflect.Indirect(reflect.ValueOf(dst))
+	elemTyp := dstTyp.Elem()
+	isPtrType := false
+	if isSlice {
+		elemTyp = elemTyp.Elem()
+		if elemTyp.Kind() == reflect.Ptr {
+			isPtrType = true
+			elemTyp = elemTyp.Elem()
+		}
+		prepareSlice(dstValue, opts)
+	}
+	// the existing elements kept by Append are not grouped with the rows
+	base := 0
+	if isSlice {
+		base = dstValue.Len()
+	}
+
+	m, err := getJoinMapping(db, columns, elemTyp, key)
+	if err != nil {
+		return false, err
+	}
+	if err = checkColumns(db, opts, key, elemTyp, func() *columnMismatch {
+		return m.mismatch(db, columns, elemTyp)
+	}); err != nil {
+		return false, err
+	}
+
+	row := newFields(len(columns))
+	defer releaseFields(row)
+
+	type childKey struct {
+		parent int
+		child  int
+		pk     interface{}
+	}
+	parents := map[interface{}]int{}
+	childrenSeen := map[childKey]bool{}
+	notFound := true
+	for rows.Next() {
+		if err = rows.Scan(row...); err != nil {
+			return notFound, err
+		}
+
+		pkField := row[m.pkColumn].(*Field)
+		if pkField.null {
+			continue
+		}
+		pk := pkField.key()
+		parentIdx, ok := parents[pk]
+		if !ok {
+			if !isSlice && !notFound {
+				continue
+			}
+			parentIdx = base + len(parents)
+			parents[pk] = parentIdx
+			var parentVal reflect.Value
+			if isSlice {
+				if isPtrType && opts.reuse {
+					parentVal = reusableElem(dstValue)
+				}
+				if !parentVal.IsValid() {
+					parentVal = reflect.Indirect(reflect.New(elemTyp))
+				}
+			} else {
+				parentVal = dstValue
+				for _, child := range m.children {
+					childVal := parentVal.Field(child.fieldIdx)
+					childVal.Set(reflect.Zero(childVal.Type()))
+				}
+			}
+			for i, fieldIdx := range m.columnMap {
+				if err = convertField(db, row[i].(*Field), columns[i], parentVal, fieldIdx); err != nil {
+					return notFound, err
+				}
+			}
+			if isSlice {
+				if isPtrType {
+					dstValue.Set(reflect.Append(dstValue, parentVal.Addr()))
+				} else {
+					dstValue.Set(reflect.Append(dstValue, parentVal))
+				}
+			}
+		}
+		notFound = false
+
+		parentVal := dstValue
+		if isSlice {
+			parentVal = reflect.Indirect(dstValue.Index(parentIdx))
+		}
+		for childIdx, child := range m.children {
+			if child.pkColumn >= 0 {
+				childPk := row[child.pkColumn].(*Field)
+				if childPk.null {
+					continue
+				}
+				k := childKey{parentIdx, childIdx, childPk.key()}
+				if childrenSeen[k] {
+					continue
+				}
+				childrenSeen[k] = true
+			} else {
+				allNull := true
+				for i := range child.columnMap {
+					if !row[i].(*Field).null {
+						allNull = false
+						break
+					}
+				}
+				if allNull {
+					continue
+				}
+			}
+
+			childVal := reflect.Indirect(reflect.New(child.elemTyp))
+			for i, fieldIdx := range child.columnMap {
+				if err = convertField(db, row[i].(*Field), columns[i], childVal, fieldIdx); err != nil {
+					return notFound, err
+				}
+			}
+			sliceVal := parentVal.Field(child.fieldIdx)
+			if child.isPtr {
+				sliceVal.Set(reflect.Append(sliceVal, childVal.Addr()))
+			} else {
+				sliceVal.Set(reflect.Append(sliceVal, childVal))
+			}
+		}
+	}
+
+	return notFound, rows.Err()
+}
+
+// key returns a comparable value of the field used to group rows.
+func (field *Field) key() interface{} {
+	switch field.typ {
+	case reflect.Int64:
+		return *(*int64)(field.ptr)
+	case reflect.Float64:
+		return *(*float64)(field.ptr)
+	case reflect.Bool:
+		return *(*bool)(field.ptr)
+	case reflect.Slice: // *[]byte
+		return string(*(*[]byte)(field.ptr))
+	case reflect.String:
+		return *(*string)(field.ptr)
+	case reflect.Struct: // time.Time
+		return (*(*time.Time)(field.ptr)).UnixNano()
+	default:
+	}
+	return nil
+}
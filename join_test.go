@@ -0,0 +1,96 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_splitColumn(t *testing.T) {
+	tests := []struct {
+		column string
+		prefix string
+		name   string
+	}{
+		{"id", "", "id"},
+		{"o.id", "o", "id"},
+		{"o__user_id", "o", "user_id"},
+		{"user_id", "", "user_id"},
+	}
+	for _, tt := range tests {
+		prefix, name := splitColumn(tt.column)
+		if prefix != tt.prefix || name != tt.name {
+			t.Fatalf("splitColumn(%v) = %v, %v, want %v, %v", tt.column, prefix, name, tt.prefix, tt.name)
+		}
+	}
+}
+
+func Test_rowsToJoined(t *testing.T) {
+	type Order struct {
+		Id     int64 `db:"id"`
+		UserId int64 `db:"user_id"`
+		Amount int64 `db:"amount"`
+	}
+	type User struct {
+		Id     int64   `db:"id"`
+		Name   string  `db:"name"`
+		Orders []Order `join:"o"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	rows := [][]driver.Value{
+		{int64(1), "a", int64(10), int64(1), int64(100)},
+		{int64(1), "a", int64(11), int64(1), int64(110)},
+		{int64(2), "b", nil, nil, nil},
+	}
+	want := []User{
+		{1, "a", []Order{{10, 1, 100}, {11, 1, 110}}},
+		{2, "b", nil},
+	}
+	server.respond("select u.*, o.*", fakeResultSet{[]string{"id", "name", "id", "user_id", "amount"}, rows})
+	server.respond("select aliased", fakeResultSet{[]string{"id", "name", "o.id", "o__user_id", "o.amount"}, rows})
+	for _, query := range []string{"select u.*, o.*", "select aliased"} {
+		var users []User
+		if _, err := db.Select(&users, query); err != nil || !reflect.DeepEqual(users, want) {
+			t.Fatalf("%v: %+v, %v", query, users, err)
+		}
+	}
+
+	server.respond("select u.*, o.*, x.*", fakeResultSet{[]string{"id", "name", "id", "amount", "id"}, rows})
+	var users []User
+	if _, err := db.Select(&users, "select u.*, o.*, x.*"); err == nil || !strings.Contains(err.Error(), "duplicate columns") {
+		t.Fatalf("unmapped duplicate columns should fail: %v", err)
+	}
+	if !hasJoinFields(db, reflect.TypeOf(User{})) || hasJoinFields(db, reflect.TypeOf(Order{})) {
+		t.Fatalf("hasJoinFields failed")
+	}
+}
+
+func Test_rowsToJoined_undeclaredPrefix(t *testing.T) {
+	type Order struct {
+		Id        int64 `db:"id"`
+		CreatedAt int64 `db:"created__at"`
+	}
+	type User struct {
+		Id        int64   `db:"id"`
+		CreatedAt int64   `db:"created__at"`
+		Nick      string  `db:"u.nick"`
+		Orders    []Order `join:"o"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select joined", fakeResultSet{
+		[]string{"id", "created__at", "u.nick", "o__id", "o.created__at"},
+		[][]driver.Value{{int64(1), int64(100), "a", int64(10), int64(200)}},
+	})
+	var users []User
+	if _, err := db.Select(&users, "select joined"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []User{{1, 100, "a", []Order{{10, 200}}}}; !reflect.DeepEqual(users, want) {
+		t.Fatalf("%+v", users)
+	}
+}
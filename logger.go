@@ -0,0 +1,167 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// LogEntry describes an executed statement. There's no connection id since database/sql
+// doesn't expose the connections of the pooled statements and sql.Tx, TxID identifies the
+// connection of the statements in a Tx.
+type LogEntry struct {
+	Level    LogLevel
+	OpType   string
+	Query    string
+	Args     []interface{}
+	Duration time.Duration
+	// Rows is the number of the rows returned by the selects or affected by the others.
+	Rows int64
+	Err  error
+	// TxID is the id of the Tx, 0 if the statement doesn't run in a Tx.
+	TxID uint64
+}
+
+type Logger interface {
+	Log(ctx context.Context, entry *LogEntry)
+}
+
+// LoggerFunc adapts a func to Logger.
+type LoggerFunc func(ctx context.Context, entry *LogEntry)
+
+func (f LoggerFunc) Log(ctx context.Context, entry *LogEntry) {
+	f(ctx, entry)
+}
+
+type stdLogger struct {
+	logger *log.Logger
+}
+
+// NewStdLogger returns a Logger writing to l, or to log.Default() if l is nil.
+func NewStdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &stdLogger{logger: l}
+}
+
+func (l *stdLogger) Log(ctx context.Context, entry *LogEntry) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[sqlw %v] op=%v duration=%v rows=%v", entry.Level, entry.OpType, entry.Duration, entry.Rows)
+	if entry.TxID > 0 {
+		fmt.Fprintf(&b, " tx=%v", entry.TxID)
+	}
+	fmt.Fprintf(&b, " query=%q args=%v", entry.Query, entry.Args)
+	if entry.Err != nil {
+		fmt.Fprintf(&b, " err=%q", entry.Err.Error())
+	}
+	l.logger.Print(b.String())
+}
+
+func (db *DB) Logger() Logger {
+	return db.logger
+}
+
+// SetLogger sets the logger of the executed statements, nil disables it.
+// The errors are logged at LevelError, Prepare/Begin/Commit/Rollback at LevelDebug and the
+// others at LevelInfo.
+func (db *DB) SetLogger(logger Logger) {
+	db.logger = logger
+}
+
+func (db *DB) LogLevel() LogLevel {
+	return db.logLevel
+}
+
+// SetLogLevel sets the minimum level of the logged statements, the default is LevelInfo,
+// LevelError logs the errors only.
+func (db *DB) SetLogLevel(level LogLevel) {
+	db.logLevel = level
+}
+
+// SetLogSampling logs the statements without errors at the rate in (0, 1], the errors are always logged.
+func (db *DB) SetLogSampling(rate float64) {
+	db.logSampling = rate
+}
+
+// PrintSql sets f to receive the same text as Result.Sql() of every statement except
+// Prepare/Begin/Commit/Rollback, it's independent of the Logger and ignores the log level
+// and sampling. A nil f disables it.
+func (db *DB) PrintSql(f func(string)) {
+	db.printSql = f
+}
+
+func logLevel(info *QueryInfo) LogLevel {
+	switch {
+	case info.Err != nil:
+		return LevelError
+	case info.OpType == opTypPrepare || info.OpType == opTypBegin || info.OpType == opTypCommit || info.OpType == opTypRollback:
+		return LevelDebug
+	default:
+		return LevelInfo
+	}
+}
+
+func (db *DB) log(ctx context.Context, info *QueryInfo) {
+	if db.printSql != nil {
+		switch info.OpType {
+		case opTypPrepare, opTypBegin, opTypCommit, opTypRollback:
+		default:
+			db.printSql(fmt.Sprintf(`[%s], %v`, info.Query, info.RedactedArgs()))
+		}
+	}
+	if db.logger == nil {
+		return
+	}
+	level := logLevel(info)
+	if level < db.logLevel {
+		return
+	}
+	if level < LevelError && db.logSampling > 0 && db.logSampling < 1 && rand.Float64() >= db.logSampling {
+		return
+	}
+	entry := &LogEntry{
+		Level:    level,
+		OpType:   info.OpType,
+		Query:    info.Query,
+		Args:     info.RedactedArgs(),
+		Duration: info.Duration,
+		Rows:     info.RowsReturned + info.RowsAffected,
+		Err:      info.Err,
+	}
+	if info.tx != nil {
+		entry.TxID = info.tx.id
+	}
+	db.logger.Log(ctx, entry)
+}
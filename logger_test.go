@@ -0,0 +1,104 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_logLevel(t *testing.T) {
+	tests := []struct {
+		info *QueryInfo
+		want LogLevel
+	}{
+		{&QueryInfo{OpType: OpSelect}, LevelInfo},
+		{&QueryInfo{OpType: OpExec}, LevelInfo},
+		{&QueryInfo{OpType: OpBegin}, LevelDebug},
+		{&QueryInfo{OpType: OpCommit}, LevelDebug},
+		{&QueryInfo{OpType: OpPrepare}, LevelDebug},
+		{&QueryInfo{OpType: OpCommit, Err: errors.New("x")}, LevelError},
+		{&QueryInfo{OpType: OpSelect, Err: errors.New("x")}, LevelError},
+	}
+	for _, tt := range tests {
+		if got := logLevel(tt.info); got != tt.want {
+			t.Fatalf("logLevel(%v, %v) = %v, want %v", tt.info.OpType, tt.info.Err, got, tt.want)
+		}
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	var ops []string
+	db.SetLogger(LoggerFunc(func(ctx context.Context, entry *LogEntry) {
+		ops = append(ops, entry.Level.String()+" "+entry.OpType)
+	}))
+	run := func() {
+		ops = nil
+		tx, _ := db.Begin()
+		tx.Exec("delete from users")
+		tx.Exec("delete from fail")
+		tx.Commit()
+	}
+
+	run()
+	if !reflect.DeepEqual(ops, []string{"INFO exec", "ERROR exec"}) {
+		t.Fatalf("info: %q", ops)
+	}
+	db.SetLogLevel(LevelDebug)
+	run()
+	if !reflect.DeepEqual(ops, []string{"DEBUG begin", "INFO exec", "ERROR exec", "DEBUG commit"}) {
+		t.Fatalf("debug: %q", ops)
+	}
+	db.SetLogLevel(LevelError)
+	run()
+	if !reflect.DeepEqual(ops, []string{"ERROR exec"}) {
+		t.Fatalf("error: %q", ops)
+	}
+}
+
+func TestSetLogSampling(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	var ops []string
+	db.SetLogger(LoggerFunc(func(ctx context.Context, entry *LogEntry) {
+		ops = append(ops, entry.Query)
+	}))
+	var printed []string
+	db.PrintSql(func(s string) {
+		printed = append(printed, s)
+	})
+	db.SetLogLevel(LevelError)
+	db.SetLogSampling(1e-9)
+	for i := 0; i < 100; i++ {
+		db.Exec("delete from users where id=?", i)
+	}
+	db.Exec("delete from fail")
+	if !reflect.DeepEqual(ops, []string{"delete from fail"}) {
+		t.Fatalf("logged: %q", ops)
+	}
+	if len(printed) != 101 || printed[0] != "[delete from users where id=?], [0]" {
+		t.Fatalf("printed: %v, %q", len(printed), printed[0])
+	}
+
+	ops = nil
+	db.SetLogLevel(LevelInfo)
+	db.SetLogSampling(1)
+	for i := 0; i < 10; i++ {
+		db.Exec("delete from users")
+	}
+	if len(ops) != 10 {
+		t.Fatalf("rate 1: %v", len(ops))
+	}
+	ops = nil
+	db.SetLogSampling(1e-9)
+	for i := 0; i < 10; i++ {
+		db.Exec("delete from users")
+	}
+	if len(ops) != 0 {
+		t.Fatalf("rate 1e-9: %v", len(ops))
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+func queryMultiContext(db *DB, ctx context.Context, selector Selector, dsts []interface{}, query string, args ...interface{}) (Result, error) {
+	opts, args := parseQueryOptions(args)
+	info := newQueryInfo(selector, nil, opTypSelect, query, args)
+	return db.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		query, args := info.Query, info.Args
+		rows, err := selector.QueryContext(ctx, info.driverQuery, info.driverArgs...)
+		if err != nil {
+			return newResult(db, nil, query, args, false), err
+		}
+		defer rows.Close()
+
+		notFound, err := rowsToMulti(db, rows, info, dsts, query, opts)
+		return newResult(db, nil, query, args, notFound), err
+	})
+}
+
+// rowsToMulti maps the result sets into dsts in order, a nil dst skips its result set.
+// notFound is true if all the result sets are empty.
+func rowsToMulti(db *DB, rows *sql.Rows, info *QueryInfo, dsts []interface{}, query string, opts *queryOptions) (bool, error) {
+	notFound := true
+	for i, dst := range dsts {
+		if i > 0 && !rows.NextResultSet() {
+			if err := rows.Err(); err != nil {
+				return notFound, err
+			}
+			return notFound, fmt.Errorf("[sqlw %v] result sets num %v is less than dests num %v", opTypSelect, i, len(dsts))
+		}
+		if dst == nil {
+			continue
+		}
+		// the result sets of the same query have different columns
+		key := sqlMappingKey(opTypSelect, fmt.Sprintf("%v#%v", query, i), reflect.TypeOf(dst))
+		setNotFound, err := info.mapRows(dst, opts, func() (bool, error) {
+			return rowsToDst(db, rows, dst, key, opts)
+		})
+		if err != nil {
+			return notFound, err
+		}
+		notFound = notFound && setNotFound
+	}
+	return notFound, rows.Err()
+}
+
+// rowsToScalar scans the first row of a single column into dst.
+func rowsToScalar(db *DB, rows *sql.Rows, dst interface{}) (bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	if len(columns) != 1 {
+		return false, fmt.Errorf("[sqlw %v] invalid columns num %v for dest type %v, should be 1", opTypSelect, len(columns), reflect.TypeOf(dst))
+	}
+	if !rows.Next() {
+		return true, rows.Err()
+	}
+	dstValue := reflect.Indirect(reflect.ValueOf(dst))
+	if db.needsField(dstValue.Type()) {
+		field := &Field{}
+		if err = rows.Scan(field); err != nil {
+			return false, err
+		}
+		return false, convertField(db, field, columns[0], dstValue, -1)
+	}
+	return false, rows.Scan(dst)
+}
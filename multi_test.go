@@ -0,0 +1,52 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestQueryMulti(t *testing.T) {
+	type User struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("call multi(?)",
+		fakeResultSet{[]string{"id", "name"}, [][]driver.Value{{int64(1), "a"}}},
+		fakeResultSet{[]string{"id", "age"}, [][]driver.Value{{int64(2), int64(20)}, {int64(3), int64(30)}}},
+		fakeResultSet{[]string{"total"}, [][]driver.Value{{int64(2)}}},
+	)
+
+	// run twice to map with the cached mappings
+	for i := 0; i < 2; i++ {
+		var named, aged []User
+		var total int64
+		_, err := db.QueryMulti([]interface{}{&named, &aged, &total}, "call multi(?)", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(named, []User{{1, "a", 0}}) || !reflect.DeepEqual(aged, []User{{2, "", 20}, {3, "", 30}}) || total != 2 {
+			t.Fatalf("%v: %+v, %+v, %v", i, named, aged, total)
+		}
+	}
+	typ := reflect.TypeOf(&[]User{})
+	for _, key := range []string{sqlMappingKey(opTypSelect, "call multi(?)#0", typ), sqlMappingKey(opTypSelect, "call multi(?)#1", typ)} {
+		if _, ok := db.mapping.Load(key); !ok {
+			t.Fatalf("mapping key %q not found", key)
+		}
+	}
+
+	var aged []User
+	if _, err := db.QueryMulti([]interface{}{nil, &aged}, "call multi(?)", 1); err != nil || len(aged) != 2 {
+		t.Fatalf("skip: %+v, %v", aged, err)
+	}
+	if _, err := db.QueryMulti([]interface{}{nil, nil, nil, &aged}, "call multi(?)", 1); err == nil {
+		t.Fatalf("more dests than result sets should fail")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy returns the column name of a struct field without tag name.
+type NamingStrategy func(fieldName string) string
+
+var (
+	// NamingExact uses the field name as it is.
+	NamingExact NamingStrategy = func(fieldName string) string { return fieldName }
+	// NamingLower uses the lower-case field name, UserID -> userid.
+	NamingLower NamingStrategy = strings.ToLower
+	// NamingSnake uses the snake_case field name, UserID -> user_id.
+	NamingSnake NamingStrategy = toSnakeCase
+)
+
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (db *DB) NamingStrategy() NamingStrategy {
+	return db.naming
+}
+
+// SetNamingStrategy sets the strategy for the exported fields without tag name, such as NamingSnake.
+// The default nil skips these fields.
+func (db *DB) SetNamingStrategy(naming NamingStrategy) {
+	db.naming = naming
+	db.resetMapping()
+}
+
+func (db *DB) CaseInsensitive() bool {
+	return db.caseInsensitive
+}
+
+// SetCaseInsensitive makes the result columns match the field names case-insensitively,
+// which helps with the databases returning upper-case columns.
+func (db *DB) SetCaseInsensitive(caseInsensitive bool) {
+	db.caseInsensitive = caseInsensitive
+	db.resetMapping()
+}
+
+// resetMapping drops all the cached mappings, which depend on the field names.
+func (db *DB) resetMapping() {
+	db.mapping.Range(func(k, v interface{}) bool {
+		db.mapping.Delete(k)
+		return true
+	})
+}
+
+// columnKey returns the key used to match column and field names.
+func (db *DB) columnKey(name string) string {
+	if db.caseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// fieldNameByNaming returns the column name of the field without tag name,
+// the unexported, embedded, join and relation fields are skipped.
+func (db *DB) fieldNameByNaming(field *reflect.StructField) string {
+	if db.naming == nil || field.PkgPath != "" || field.Anonymous {
+		return ""
+	}
+	if _, ok := field.Tag.Lookup(joinTag); ok {
+		return ""
+	}
+	if _, ok := field.Tag.Lookup(relTag); ok {
+		return ""
+	}
+	return db.naming(field.Name)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func Test_toSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Id":         "id",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"CreatedAt":  "created_at",
+		"Addr2Line":  "addr2_line",
+		"name":       "name",
+	}
+	for name, want := range tests {
+		if got := toSnakeCase(name); got != want {
+			t.Fatalf("toSnakeCase(%v) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+type namingProfile struct {
+	Bio string
+}
+
+type namingUser struct {
+	Id       int64
+	UserName string
+	secret   string
+	namingProfile
+	Orders []namingOrder `join:"o"`
+	Items  []namingOrder `rel:"id=user_id"`
+}
+
+type namingOrder struct {
+	Id int64 `db:"id"`
+}
+
+func TestSetNamingStrategy(t *testing.T) {
+	t.Run("select", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		type User struct {
+			Id       int64
+			UserName string
+			secret   string
+		}
+		server.respond("select id,user_name from users", fakeResultSet{[]string{"id", "user_name"}, [][]driver.Value{{int64(1), "a"}}})
+		var users []User
+		if _, err := db.Select(&users, "select id,user_name from users"); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Id != 0 || users[0].UserName != "" {
+			t.Fatalf("untagged fields should be skipped without naming strategy: %+v", users)
+		}
+
+		db.SetNamingStrategy(NamingSnake)
+		users = nil
+		if _, err := db.Select(&users, "select id,user_name from users"); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Id != 1 || users[0].UserName != "a" {
+			t.Fatalf("users: %+v", users)
+		}
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		db.SetNamingStrategy(NamingSnake)
+		user := namingUser{Id: 1, UserName: "a", secret: "s", namingProfile: namingProfile{"b"}}
+		if _, err := db.Insert("insert into users", &user); err != nil {
+			t.Fatal(err)
+		}
+		last := server.last()
+		if last.query != "insert into users(id,user_name) values(?,?)" || !reflect.DeepEqual(last.args, []driver.Value{int64(1), "a"}) {
+			t.Fatalf("last: %+v", last)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		db, server := newFakeDB(t, "fakemysql")
+		db.SetNamingStrategy(NamingSnake)
+		user := namingUser{Id: 1, UserName: "a", secret: "s", namingProfile: namingProfile{"b"}}
+		if _, err := db.Update("update users set ", &user); err != nil {
+			t.Fatal(err)
+		}
+		last := server.last()
+		if last.query != "update users set id=?,user_name=?" || !reflect.DeepEqual(last.args, []driver.Value{int64(1), "a"}) {
+			t.Fatalf("last: %+v", last)
+		}
+	})
+}
+
+func TestSetCaseInsensitive(t *testing.T) {
+	type User struct {
+		Id       int64  `db:"id"`
+		UserName string `db:"user_name"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select ID,USER_NAME from users", fakeResultSet{[]string{"ID", "USER_NAME"}, [][]driver.Value{{int64(1), "a"}}})
+	var users []User
+	if _, err := db.Select(&users, "select ID,USER_NAME from users"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, []User{{}}) {
+		t.Fatalf("upper-case columns should not match by default: %+v", users)
+	}
+
+	db.SetCaseInsensitive(true)
+	users = nil
+	if _, err := db.Select(&users, "select ID,USER_NAME from users"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, []User{{1, "a"}}) {
+		t.Fatalf("users: %+v", users)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+// QueryOption customizes a single Query/QueryRow/Select call. It is passed
+// together with the args and removed from them before calling the driver:
+//
+//	db.Select(&users, "select * from users where age>?", sqlw.KeyBy("id"), 18)
+type QueryOption func(opts *queryOptions)
+
+type queryOptions struct {
+	keyBy          string
+	columnCheck    ColumnCheck
+	hasColumnCheck bool
+	appendRows     bool
+	capacity       int
+	reuse          bool
+}
+
+func (opts *queryOptions) getColumnCheck(db *DB) ColumnCheck {
+	if opts.hasColumnCheck {
+		return opts.columnCheck
+	}
+	return db.columnCheck
+}
+
+// KeyBy sets the column used as the key of map destinations such as *map[K]*T and *map[K][]*T.
+func KeyBy(column string) QueryOption {
+	return func(opts *queryOptions) {
+		opts.keyBy = column
+	}
+}
+
+// CheckColumns overrides DB.ColumnCheck for a single call.
+func CheckColumns(check ColumnCheck) QueryOption {
+	return func(opts *queryOptions) {
+		opts.columnCheck = check
+		opts.hasColumnCheck = true
+	}
+}
+
+// Append keeps the existing elements of the slice and map destinations and appends the rows to them.
+func Append() QueryOption {
+	return func(opts *queryOptions) {
+		opts.appendRows = true
+	}
+}
+
+// Capacity grows the slice destinations to hold n more elements before scanning, and sizes
+// the nil map destinations.
+func Capacity(n int) QueryOption {
+	return func(opts *queryOptions) {
+		opts.capacity = n
+	}
+}
+
+// Reuse scans the rows into the elements already pointed to by the backing array of []*T destinations
+// instead of allocating new ones, the callers must not keep these pointers from the previous calls.
+// It's not supported by map destinations.
+func Reuse() QueryOption {
+	return func(opts *queryOptions) {
+		opts.reuse = true
+	}
+}
+
+var emptyQueryOptions = &queryOptions{}
+
+func parseQueryOptions(args []interface{}) (*queryOptions, []interface{}) {
+	found := false
+	for _, v := range args {
+		if _, ok := v.(QueryOption); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return emptyQueryOptions, args
+	}
+
+	opts := &queryOptions{}
+	realArgs := make([]interface{}, 0, len(args)-1)
+	for _, v := range args {
+		if opt, ok := v.(QueryOption); ok {
+			opt(opts)
+		} else {
+			realArgs = append(realArgs, v)
+		}
+	}
+	return opts, realArgs
+}
@@ -0,0 +1,316 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// relTag declares a relation loaded by Preload, the options are:
+//
+//	has_one/has_many/belongs_to: relation kind
+//	fk=xxx:    the foreign key column, on the related table for has_one/has_many and on this table for belongs_to
+//	ref=xxx:   the referenced column, defaults to the primary key of this table for has_one/has_many
+//	           and of the related table for belongs_to
+//	table=xxx: the related table, defaults to TableName() of the related struct
+//
+// e.g.
+//
+//	type User struct {
+//		Id     int64    `db:"id,pk"`
+//		Orders []*Order `db:"-" rel:"has_many,fk=user_id,table=orders"`
+//	}
+const relTag = "rel"
+
+const (
+	relHasOne    = "has_one"
+	relHasMany   = "has_many"
+	relBelongsTo = "belongs_to"
+)
+
+// TableNamer is implemented by structs to provide the table name used by Preload.
+type TableNamer interface {
+	TableName() string
+}
+
+type relation struct {
+	kind       string
+	table      string
+	fieldIdx   int
+	fieldTyp   reflect.Type
+	elemTyp    reflect.Type // the related struct
+	keyField   int          // field index on the parent holding the key
+	column     string       // column on the related table to match with
+	matchField int          // field index on the related struct holding the column value
+}
+
+func parseRelation(db *DB, typ reflect.Type, name string) (*relation, error) {
+	strField, ok := typ.FieldByName(name)
+	if !ok || len(strField.Index) != 1 {
+		return nil, fmt.Errorf("[sqlw %v] field %v not found in %v", opTypSelect, name, typ)
+	}
+	tag, ok := strField.Tag.Lookup(relTag)
+	if !ok {
+		return nil, fmt.Errorf("[sqlw %v] field %v.%v doesn't have tag %q", opTypSelect, typ, name, relTag)
+	}
+	kind, opts := parseTag(tag)
+
+	rel := &relation{kind: kind, fieldIdx: strField.Index[0], fieldTyp: strField.Type}
+	elemTyp := strField.Type
+	if kind == relHasMany {
+		if elemTyp.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("[sqlw %v] invalid %v field type %v.%v: %v", opTypSelect, kind, typ, name, strField.Type)
+		}
+		elemTyp = elemTyp.Elem()
+	}
+	if elemTyp.Kind() == reflect.Ptr {
+		elemTyp = elemTyp.Elem()
+	}
+	if elemTyp.Kind() != reflect.Struct || isScalarType(db, elemTyp) {
+		return nil, fmt.Errorf("[sqlw %v] invalid %v field type %v.%v: %v", opTypSelect, kind, typ, name, strField.Type)
+	}
+	rel.elemTyp = elemTyp
+
+	rel.table, _ = opts.Get("table")
+	if rel.table == "" {
+		namer, ok := reflect.New(elemTyp).Interface().(TableNamer)
+		if !ok {
+			return nil, fmt.Errorf("[sqlw %v] table of %v.%v not specified", opTypSelect, typ, name)
+		}
+		rel.table = namer.TableName()
+	}
+
+	fk, _ := opts.Get("fk")
+	ref, _ := opts.Get("ref")
+	if fk == "" {
+		return nil, fmt.Errorf("[sqlw %v] fk of %v.%v not specified", opTypSelect, typ, name)
+	}
+
+	fieldIdx := func(typ reflect.Type, column string) int {
+		if column == "" {
+			return -1
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			strField := typ.Field(i)
+			if db.columnKey(db.parseFieldName(&strField)) == db.columnKey(column) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	var parentColumn string
+	switch kind {
+	case relHasOne, relHasMany:
+		// parent.ref = child.fk
+		parentColumn, rel.column = ref, fk
+		if parentColumn == "" {
+			_, parentColumn = pkFieldIdx(db, typ)
+		}
+	case relBelongsTo:
+		// parent.fk = child.ref
+		parentColumn, rel.column = fk, ref
+		if rel.column == "" {
+			_, rel.column = pkFieldIdx(db, elemTyp)
+		}
+	default:
+		return nil, fmt.Errorf("[sqlw %v] invalid relation %q of %v.%v", opTypSelect, kind, typ, name)
+	}
+	if rel.keyField = fieldIdx(typ, parentColumn); rel.keyField < 0 {
+		return nil, fmt.Errorf("[sqlw %v] column %q of %v.%v not found in %v", opTypSelect, parentColumn, typ, name, typ)
+	}
+	if rel.matchField = fieldIdx(elemTyp, rel.column); rel.matchField < 0 {
+		return nil, fmt.Errorf("[sqlw %v] column %q of %v.%v not found in %v", opTypSelect, rel.column, typ, name, elemTyp)
+	}
+
+	return rel, nil
+}
+
+func getRelation(db *DB, typ reflect.Type, name string) (*relation, error) {
+	key := sqlMappingKey(relTag, name, typ)
+	if stored, ok := db.mapping.Load(key); ok {
+		return stored.(*relation), nil
+	}
+	rel, err := parseRelation(db, typ, name)
+	if err != nil {
+		return nil, err
+	}
+	db.mapping.Store(key, rel)
+	return rel, nil
+}
+
+// preloadBatchSize is the max number of the keys of each preloading query, it's far below
+// the placeholder limits of MySQL and PostgreSQL.
+var preloadBatchSize = 1000
+
+// relKey normalizes key values so that the integer columns of different types, such as
+// int32, int64 and uint64, match each other.
+func relKey(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+		return v.Uint()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		return nil
+	default:
+	}
+	return v.Interface()
+}
+
+// padKeys pads the keys to a power of two, or preloadBatchSize, by repeating the last one,
+// which bounds the number of the distinct preloading queries in the mapping cache. The
+// capacity of keys must be its length so that appending copies it.
+func padKeys(keys []interface{}) []interface{} {
+	n := 1
+	for n < len(keys) {
+		n <<= 1
+	}
+	if n > preloadBatchSize {
+		n = preloadBatchSize
+	}
+	for len(keys) < n {
+		keys = append(keys, keys[len(keys)-1])
+	}
+	return keys
+}
+
+func preloadQuery(db *DB, rel *relation, n int) string {
+	var b strings.Builder
+	b.WriteString("select * from ")
+	b.WriteString(rel.table)
+	b.WriteString(" where ")
+	b.WriteString(db.quote + rel.column + db.quote)
+	b.WriteString(" in (")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(db.placeholderBuilder(i + 1))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func preloadContext(db *DB, ctx context.Context, selector Selector, dst interface{}, fields ...string) error {
+	var parents []reflect.Value
+	typ := reflect.TypeOf(dst)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, typ)
+	}
+	dstValue := reflect.ValueOf(dst)
+	switch {
+	case isStructPtr(typ):
+		if dstValue.IsNil() {
+			return nil
+		}
+		parents = append(parents, dstValue.Elem())
+		typ = typ.Elem()
+	case isStructSlicePtr(db, typ):
+		dstValue = dstValue.Elem()
+		typ = typ.Elem().Elem()
+		isPtrType := typ.Kind() == reflect.Ptr
+		if isPtrType {
+			typ = typ.Elem()
+		}
+		for i := 0; i < dstValue.Len(); i++ {
+			v := dstValue.Index(i)
+			if isPtrType {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+			parents = append(parents, v)
+		}
+	default:
+		return fmt.Errorf("[sqlw %v] invalid dest type: %v", opTypSelect, typ)
+	}
+
+	for _, name := range fields {
+		rel, err := getRelation(db, typ, name)
+		if err != nil {
+			return err
+		}
+
+		var args []interface{}
+		keys := map[interface{}]struct{}{}
+		for _, parent := range parents {
+			keyVal := parent.Field(rel.keyField)
+			k := relKey(keyVal)
+			if k == nil {
+				continue
+			}
+			if _, ok := keys[k]; !ok {
+				keys[k] = empty
+				args = append(args, reflect.Indirect(keyVal).Interface())
+			}
+		}
+
+		children := reflect.New(reflect.SliceOf(reflect.PtrTo(rel.elemTyp)))
+		for len(args) > 0 {
+			n := len(args)
+			if n > preloadBatchSize {
+				n = preloadBatchSize
+			}
+			batch := padKeys(args[:n:n])
+			args = args[n:]
+			if _, err = queryContext(db, ctx, selector, children.Interface(), preloadQuery(db, rel, len(batch)), append(batch, Append())...); err != nil {
+				return err
+			}
+		}
+
+		grouped := map[interface{}][]reflect.Value{}
+		children = children.Elem()
+		for i := 0; i < children.Len(); i++ {
+			child := children.Index(i)
+			k := relKey(child.Elem().Field(rel.matchField))
+			grouped[k] = append(grouped[k], child)
+		}
+
+		for _, parent := range parents {
+			fieldVal := parent.Field(rel.fieldIdx)
+			fieldVal.Set(reflect.Zero(rel.fieldTyp))
+			matched := grouped[relKey(parent.Field(rel.keyField))]
+			if len(matched) == 0 {
+				continue
+			}
+			if rel.kind == relHasMany {
+				isPtrElem := rel.fieldTyp.Elem().Kind() == reflect.Ptr
+				sliceVal := reflect.MakeSlice(rel.fieldTyp, 0, len(matched))
+				for _, child := range matched {
+					if isPtrElem {
+						sliceVal = reflect.Append(sliceVal, child)
+					} else {
+						sliceVal = reflect.Append(sliceVal, child.Elem())
+					}
+				}
+				fieldVal.Set(sliceVal)
+			} else if rel.fieldTyp.Kind() == reflect.Ptr {
+				fieldVal.Set(matched[0])
+			} else {
+				fieldVal.Set(matched[0].Elem())
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type preloadOrder struct {
+	Id     int64        `db:"id"`
+	UserId uint64       `db:"user_id"`
+	User   *preloadUser `db:"-" rel:"belongs_to,fk=user_id,table=users"`
+}
+
+type preloadUser struct {
+	Id     int32           `db:"id,pk"`
+	Orders []*preloadOrder `db:"-" rel:"has_many,fk=user_id,table=orders"`
+	Latest preloadOrder    `db:"-" rel:"has_one,fk=user_id,ref=id,table=orders"`
+	Bad    []preloadOrder  `db:"-" rel:"has_many,fk=missing,table=orders"`
+	NoFk   *preloadOrder   `db:"-" rel:"has_one,table=orders"`
+}
+
+func Test_parseRelation(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	userTyp, orderTyp := reflect.TypeOf(preloadUser{}), reflect.TypeOf(preloadOrder{})
+	rel, err := parseRelation(db, userTyp, "Orders")
+	if err != nil || rel.kind != relHasMany || rel.table != "orders" || rel.elemTyp != orderTyp || rel.keyField != 0 || rel.column != "user_id" || rel.matchField != 1 {
+		t.Fatalf("has_many: %+v, %v", rel, err)
+	}
+	rel, err = parseRelation(db, orderTyp, "User")
+	if err != nil || rel.kind != relBelongsTo || rel.elemTyp != userTyp || rel.keyField != 1 || rel.column != "id" || rel.matchField != 0 {
+		t.Fatalf("belongs_to: %+v, %v", rel, err)
+	}
+	for _, name := range []string{"Bad", "NoFk", "Id", "Missing"} {
+		if _, err = parseRelation(db, userTyp, name); err == nil {
+			t.Fatalf("%v should fail", name)
+		}
+	}
+}
+
+func Test_relKey(t *testing.T) {
+	i, u := int64(7), uint64(7)
+	tests := []struct {
+		v    interface{}
+		want interface{}
+	}{
+		{int32(7), int64(7)},
+		{uint64(7), int64(7)},
+		{&i, int64(7)},
+		{&u, int64(7)},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{int64(-1), int64(-1)},
+		{[]byte("a"), "a"},
+		{"a", "a"},
+		{(*int64)(nil), nil},
+	}
+	for _, tt := range tests {
+		if got := relKey(reflect.ValueOf(tt.v)); got != tt.want {
+			t.Fatalf("relKey(%#v) = %#v, want %#v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestPreload(t *testing.T) {
+	defer func(n int) { preloadBatchSize = n }(preloadBatchSize)
+	preloadBatchSize = 2
+
+	db, server := newFakeDB(t, "fakemysql")
+	cols := []string{"id", "user_id"}
+	server.respond("select * from orders where user_id in (?,?)", fakeResultSet{cols, [][]driver.Value{{int64(10), int64(1)}, {int64(11), int64(1)}, {int64(20), int64(2)}}})
+	server.respond("select * from orders where user_id in (?)", fakeResultSet{cols, [][]driver.Value{{int64(30), int64(3)}}})
+	users := []*preloadUser{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 1}}
+	if err := db.Preload(&users, "Orders"); err != nil {
+		t.Fatal(err)
+	}
+	got := make([][]int64, len(users))
+	for i, user := range users {
+		for _, order := range user.Orders {
+			got[i] = append(got[i], order.Id)
+		}
+	}
+	if !reflect.DeepEqual(got, [][]int64{{10, 11}, {20}, {30}, {10, 11}}) {
+		t.Fatalf("preloaded orders: %v", got)
+	}
+	want := []string{"select * from orders where user_id in (?,?)", "select * from orders where user_id in (?)"}
+	if queries := server.queries(); !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries: %v", queries)
+	}
+	if args := server.last().args; len(args) != 1 || args[0] != int32(3) {
+		t.Fatalf("args: %v", args)
+	}
+
+	users = []*preloadUser{{Id: 3}}
+	if err := db.Preload(&users, "Orders"); err != nil || len(users[0].Orders) != 1 || users[0].Orders[0].Id != 30 {
+		t.Fatalf("preload: %v", err)
+	}
+
+	for _, dst := range []interface{}{nil, 1, users, &[]int{1}} {
+		if err := db.Preload(dst, "Orders"); err == nil || !strings.Contains(err.Error(), "invalid dest type") {
+			t.Fatalf("preload %T: %v", dst, err)
+		}
+	}
+}
+
+func Test_padKeys(t *testing.T) {
+	keys := []interface{}{1, 2, 3, 4, 5}
+	if got := padKeys(keys[:3:3]); !reflect.DeepEqual(got, []interface{}{1, 2, 3, 3}) || keys[3] != 4 {
+		t.Fatalf("padKeys = %v, keys = %v", got, keys)
+	}
+	if got := padKeys(keys[:1:1]); len(got) != 1 {
+		t.Fatalf("padKeys = %v", got)
+	}
+	keys = make([]interface{}, preloadBatchSize-1)
+	if got := padKeys(keys[:len(keys):len(keys)]); len(got) != preloadBatchSize {
+		t.Fatalf("padKeys len = %v", len(got))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"path"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the redacted args in Result.Sql(), the loggers and the slow query logs.
+const RedactedValue = "[REDACTED]"
+
+// argColumns records the columns of the args generated from the struct fields, and whether
+// the fields have the "secret" tag option.
+type argColumns struct {
+	names   []string
+	secrets []bool
+}
+
+func (c *argColumns) add(name string, secret bool) {
+	c.names = append(c.names, name)
+	c.secrets = append(c.secrets, secret)
+}
+
+// SetRedactColumns redacts the args of the columns matching the case-insensitive patterns
+// in the generated insert/update sql, such as "password" or "*_token", see path.Match.
+func (db *DB) SetRedactColumns(patterns ...string) {
+	lowered := make([]string, len(patterns))
+	for i, p := range patterns {
+		lowered[i] = strings.ToLower(p)
+	}
+	db.redactColumns = lowered
+}
+
+// SetRedactFunc redacts the args for which f returns true, column is "" if it's unknown.
+func (db *DB) SetRedactFunc(f func(column string, value interface{}) bool) {
+	db.redactFunc = f
+}
+
+func (db *DB) redactColumn(column string) bool {
+	column = strings.ToLower(column)
+	for _, p := range db.redactColumns {
+		if ok, _ := path.Match(p, column); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArgs returns args with the redacted ones replaced, or args itself if none is redacted.
+func (db *DB) redactArgs(args []interface{}, cols *argColumns) []interface{} {
+	if db == nil || (cols == nil && db.redactFunc == nil) {
+		return args
+	}
+	var redacted []interface{}
+	for i, arg := range args {
+		var column string
+		secret := false
+		if cols != nil && i < len(cols.names) {
+			column = cols.names[i]
+			secret = cols.secrets[i] || (column != "" && db.redactColumn(column))
+		}
+		if !secret && db.redactFunc != nil {
+			secret = db.redactFunc(column, arg)
+		}
+		if secret {
+			if redacted == nil {
+				redacted = append([]interface{}{}, args...)
+			}
+			redacted[i] = RedactedValue
+		}
+	}
+	if redacted == nil {
+		return args
+	}
+	return redacted
+}
+
+// RedactedArgs returns Args with the values redacted by the "secret" tag option and the
+// rules set on DB, use it instead of Args when logging in the interceptors.
+func (info *QueryInfo) RedactedArgs() []interface{} {
+	return info.db.redactArgs(info.Args, info.cols)
+}
+
+type secretFieldsKey struct {
+	typ reflect.Type
+}
+
+// getSecretFields returns whether the fields of typ have the "secret" tag option.
+func getSecretFields(db *DB, typ reflect.Type) []bool {
+	key := secretFieldsKey{typ}
+	if stored, ok := db.mapping.Load(key); ok {
+		return stored.([]bool)
+	}
+	secrets := make([]bool, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		strField := typ.Field(i)
+		secrets[i] = db.parseFieldOptions(&strField).Contains("secret")
+	}
+	db.mapping.Store(key, secrets)
+	return secrets
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_redactArgs(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	cols := &argColumns{}
+	cols.add("name", false)
+	cols.add("password", true)
+	cols.add("API_Token", false)
+	cols.add("email", false)
+	args := []interface{}{"a", "pwd", "tok", "a@b.c"}
+
+	if got := db.redactArgs(args, nil); !reflect.DeepEqual(got, args) {
+		t.Fatalf("no columns: %v", got)
+	}
+	if got := db.redactArgs(args, cols); !reflect.DeepEqual(got, []interface{}{"a", RedactedValue, "tok", "a@b.c"}) {
+		t.Fatalf("secret: %v", got)
+	}
+	db.SetRedactColumns("*_TOKEN")
+	if got := db.redactArgs(args, cols); !reflect.DeepEqual(got, []interface{}{"a", RedactedValue, RedactedValue, "a@b.c"}) {
+		t.Fatalf("columns: %v", got)
+	}
+	var columns []string
+	db.SetRedactFunc(func(column string, value interface{}) bool {
+		columns = append(columns, column)
+		s, ok := value.(string)
+		return ok && strings.Contains(s, "@")
+	})
+	if got := db.redactArgs(args, cols); !reflect.DeepEqual(got, []interface{}{"a", RedactedValue, RedactedValue, RedactedValue}) {
+		t.Fatalf("func: %v", got)
+	}
+	if !reflect.DeepEqual(columns, []string{"name", "email"}) {
+		t.Fatalf("func columns: %v", columns)
+	}
+	if got := db.redactArgs([]interface{}{1, "x@y"}, nil); !reflect.DeepEqual(got, []interface{}{1, RedactedValue}) {
+		t.Fatalf("func without columns: %v", got)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", "pwd", "tok", "a@b.c"}) {
+		t.Fatalf("args modified: %v", args)
+	}
+}
+
+func TestResult_Sql_redacted(t *testing.T) {
+	type User struct {
+		Id       int64  `db:"id"`
+		Password string `db:"password,secret"`
+		ApiToken string `db:"api_token"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	db.SetRedactColumns("*_token")
+	result, err := db.Insert("insert into users", &User{1, "pwd", "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(result.Sql(), "], [1 [REDACTED] [REDACTED]]") {
+		t.Fatalf("sql: %v", result.Sql())
+	}
+	if last := server.last(); !reflect.DeepEqual(last.args[1:], []driver.Value{"pwd", "tok"}) {
+		t.Fatalf("driver args: %v", last.args)
+	}
+
+	result, err = db.Exec("update users set password=? where id=?", "pwd", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sql() != "[update users set password=? where id=?], [pwd 1]" {
+		t.Fatalf("raw sql: %v", result.Sql())
+	}
+}
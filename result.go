@@ -15,6 +15,7 @@ type sqlResult struct {
 	query    string
 	args     []interface{}
 	notFound bool
+	cols     *argColumns
 }
 
 type Result = *sqlResult
@@ -33,8 +34,22 @@ func (r *sqlResult) RowsAffected() (int64, error) {
 	return 0, nil
 }
 
+// Sql returns the query and the args, the args are redacted by the "secret" tag option
+// and the rules set on DB.
 func (r *sqlResult) Sql() string {
-	return fmt.Sprintf(`[%s], %v`, r.query, r.args)
+	return fmt.Sprintf(`[%s], %v`, r.query, r.db.redactArgs(r.args, r.cols))
+}
+
+// InterpolatedSql returns the query with the args inlined as dialect-specific literals, the args
+// are redacted like Sql, and the dialects other than MySQL and PostgreSQL get the standard sql
+// literals. It's only for debugging and logging, never execute the returned sql.
+func (r *sqlResult) InterpolatedSql() string {
+	db := r.db
+	if db == nil {
+		db = &DB{placeholder: "?", isMysql: true, dialect: dialectMysql}
+	}
+	query, _ := db.interpolate(r.query, db.redactArgs(r.args, r.cols), false)
+	return query
 }
 
 func (r *sqlResult) Query() string {
@@ -50,9 +65,5 @@ func (r *sqlResult) IsNotFound() bool {
 }
 
 func newResult(db *DB, r sql.Result, query string, args []interface{}, notFound bool) Result {
-	ret := &sqlResult{r, db, query, args, notFound}
-	if db != nil && db.printSql != nil {
-		db.printSql(ret.Sql())
-	}
-	return ret
+	return &sqlResult{Result: r, db: db, query: query, args: args, notFound: notFound}
 }
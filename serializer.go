@@ -0,0 +1,153 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Serializer converts the fields tagged with its registered name, such as `db:"meta,json"`,
+// to the column values and back.
+type Serializer interface {
+	// Marshal returns the value passed to the driver for v.
+	Marshal(v interface{}) (interface{}, error)
+	// Unmarshal parses data into v, which is a pointer to the field.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) (interface{}, error) {
+	if isNilValue(reflect.ValueOf(v)) {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) (interface{}, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// textSerializer works with the types implementing encoding.TextMarshaler and encoding.TextUnmarshaler.
+type textSerializer struct{}
+
+func (textSerializer) Marshal(v interface{}) (interface{}, error) {
+	m, ok := v.(encoding.TextMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("type %T doesn't implement encoding.TextMarshaler", v)
+	}
+	data, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (textSerializer) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(encoding.TextUnmarshaler)
+	if !ok {
+		return fmt.Errorf("type %T doesn't implement encoding.TextUnmarshaler", v)
+	}
+	return u.UnmarshalText(data)
+}
+
+func defaultSerializers() map[string]Serializer {
+	return map[string]Serializer{
+		"json": jsonSerializer{},
+		"gob":  gobSerializer{},
+		"text": textSerializer{},
+	}
+}
+
+// RegisterSerializer registers s for the fields tagged with the name option, "json", "gob"
+// and "text" are registered by default and can be replaced.
+func (db *DB) RegisterSerializer(name string, s Serializer) {
+	serializers := map[string]Serializer{}
+	for k, v := range db.serializers {
+		serializers[k] = v
+	}
+	serializers[name] = s
+	db.serializers = serializers
+	db.resetFieldConvertOptions()
+}
+
+// unmarshalTo sets NULL to the zero value and parses the others into dstVal, the pointer
+// fields are allocated and parsed by the pointers.
+func (field *Field) unmarshalTo(s Serializer, dstVal reflect.Value) error {
+	dstVal.Set(reflect.Zero(dstVal.Type()))
+	if field.null {
+		return nil
+	}
+	var data []byte
+	switch field.typ {
+	case reflect.Slice: // *[]byte
+		data = *(*[]byte)(field.ptr)
+	case reflect.String:
+		data = []byte(*(*string)(field.ptr))
+	default:
+		return errUnsupportedConversion(field.typ, "serialized "+dstVal.Type().String())
+	}
+	if dstVal.Kind() == reflect.Ptr {
+		elemVal := reflect.New(dstVal.Type().Elem())
+		if err := s.Unmarshal(data, elemVal.Interface()); err != nil {
+			return err
+		}
+		dstVal.Set(elemVal)
+		return nil
+	}
+	return s.Unmarshal(data, dstVal.Addr().Interface())
+}
+
+// isNilValue reports whether v is nil or a nil pointer, slice, map or interface.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	default:
+	}
+	return false
+}
+
+// encodeField returns the value passed to the driver for the fieldIdx field of structVal.
+// The serialized nil fields are NULL, and the addressable fields are marshaled by pointer
+// so that the methods with pointer receivers work.
+func (db *DB) encodeField(structVal reflect.Value, fieldIdx int) (interface{}, error) {
+	fieldVal := structVal.Field(fieldIdx)
+	if fieldOpts := getFieldConvertOptions(db, structVal.Type())[fieldIdx]; fieldOpts != nil && fieldOpts.serializer != nil {
+		if isNilValue(fieldVal) {
+			return nil, nil
+		}
+		if fieldVal.Kind() != reflect.Ptr && fieldVal.CanAddr() {
+			return fieldOpts.serializer.Marshal(fieldVal.Addr().Interface())
+		}
+		return fieldOpts.serializer.Marshal(fieldVal.Interface())
+	}
+	return db.encodeValue(fieldVal)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// ptrText implements encoding.TextMarshaler with a pointer receiver.
+type ptrText struct{ A, B int }
+
+func (p *ptrText) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d-%d", p.A, p.B)), nil
+}
+
+func (p *ptrText) UnmarshalText(data []byte) error {
+	_, err := fmt.Sscanf(string(data), "%d-%d", &p.A, &p.B)
+	return err
+}
+
+func TestSerializers(t *testing.T) {
+	type Article struct {
+		Id    int64             `db:"id"`
+		Meta  map[string]string `db:"meta,json"`
+		Tags  []string          `db:"tags,json"`
+		Blob  map[string]int    `db:"blob,gob"`
+		Text  ptrText           `db:"text,text"`
+		Owner *ptrText          `db:"owner,text"`
+	}
+	db, server := newFakeDB(t, "fakemysql")
+	server.respond("select * from articles", fakeResultSet{[]string{"id", "meta", "tags", "blob", "text", "owner"}, nil})
+
+	articles := []Article{
+		{1, map[string]string{"k": "v"}, []string{"a"}, map[string]int{"n": 1}, ptrText{1, 2}, &ptrText{3, 4}},
+		{2, nil, nil, nil, ptrText{5, 6}, nil},
+	}
+	var rows [][]driver.Value
+	for i := range articles {
+		if _, err := db.Insert("insert into articles", &articles[i]); err != nil {
+			t.Fatal(err)
+		}
+		args := server.last().args
+		for j, arg := range args {
+			if s, ok := arg.(string); ok {
+				args[j] = []byte(s)
+			}
+		}
+		rows = append(rows, args)
+	}
+	if args := server.last().args; args[1] != nil || args[2] != nil || args[3] != nil || args[5] != nil {
+		t.Fatalf("nil fields should be NULL: %#v", args)
+	}
+	if args := rows[0]; string(args[1].([]byte)) != `{"k":"v"}` || string(args[4].([]byte)) != "1-2" {
+		t.Fatalf("marshaled args: %#v", args)
+	}
+
+	server.respond("select * from articles", fakeResultSet{[]string{"id", "meta", "tags", "blob", "text", "owner"}, rows})
+	for _, rawScan := range []bool{true, false} {
+		db.SetRawScan(rawScan)
+		var got []Article
+		if _, err := db.Select(&got, "select * from articles"); err != nil || !reflect.DeepEqual(got, articles) {
+			t.Fatalf("rawScan %v: %+v, %v", rawScan, got, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowQuery describes a statement slower than the threshold set by DB.SetSlowQueryLog.
+type SlowQuery struct {
+	OpType   string
+	Query    string
+	Args     []interface{}
+	Duration time.Duration
+	// Caller is the "file:line" calling sqlw.
+	Caller string
+	InTx   bool
+	Err    error
+}
+
+func (q *SlowQuery) String() string {
+	return fmt.Sprintf("[sqlw %v] slow query %v at %v, in tx: %v, [%s], %v", q.OpType, q.Duration, q.Caller, q.InTx, q.Query, q.Args)
+}
+
+// SetSlowQueryLog reports the statements taking longer than threshold to f, the duration
+// covers the interpolation, the driver call and the scanning and mapping of the rows, as
+// QueryInfo.Duration does. A nil f disables it.
+func (db *DB) SetSlowQueryLog(threshold time.Duration, f func(SlowQuery)) {
+	db.slowThreshold = threshold
+	db.slowQueryLog = f
+}
+
+func (db *DB) logSlowQuery(info *QueryInfo) {
+	if db.slowQueryLog == nil || info.Duration < db.slowThreshold {
+		return
+	}
+	db.slowQueryLog(SlowQuery{
+		OpType:   info.OpType,
+		Query:    info.Query,
+		Args:     info.RedactedArgs(),
+		Duration: info.Duration,
+		Caller:   caller(info.callers),
+		InTx:     info.InTx,
+		Err:      info.Err,
+	})
+}
+
+// pkgDir is the directory of the sqlw sources.
+var pkgDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// callers returns the program counters of the stack calling it, they are formatted by caller
+// only for the slow queries.
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+// caller returns the file:line of the first frame outside the sqlw sources, the test files
+// of sqlw are regarded as outside.
+func caller(pcs []uintptr) string {
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && (filepath.Dir(frame.File) != pkgDir || strings.HasSuffix(frame.File, "_test.go")) {
+			return fmt.Sprintf("%v:%v", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
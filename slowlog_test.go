@@ -0,0 +1,72 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSetSlowQueryLog(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	var queries []SlowQuery
+	db.SetSlowQueryLog(0, func(q SlowQuery) {
+		queries = append(queries, q)
+	})
+	for i := 0; i < 3; i++ {
+		db.Use(func(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+			return next(ctx, info)
+		})
+	}
+
+	_, file, line, _ := runtime.Caller(0)
+	db.Exec("delete from users")
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Exec("delete from orders")
+	tx.Commit()
+
+	wants := []string{
+		fmt.Sprintf("%v:%v", file, line+1),
+		fmt.Sprintf("%v:%v", file, line+2),
+		fmt.Sprintf("%v:%v", file, line+6),
+		fmt.Sprintf("%v:%v", file, line+7),
+	}
+	if len(queries) != len(wants) {
+		t.Fatalf("queries: %+v", queries)
+	}
+	for i, q := range queries {
+		if q.Caller != wants[i] {
+			t.Fatalf("%v: caller %v, want %v", q.Query, q.Caller, wants[i])
+		}
+	}
+	if !queries[2].InTx || queries[3].OpType != OpCommit {
+		t.Fatalf("queries: %+v", queries)
+	}
+}
+
+func TestSetSlowQueryLog_threshold(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	var queries []SlowQuery
+	db.SetSlowQueryLog(time.Hour, func(q SlowQuery) {
+		queries = append(queries, q)
+	})
+	db.Exec("delete from users")
+	if len(queries) != 0 {
+		t.Fatalf("queries: %+v", queries)
+	}
+
+	db.SetSlowQueryLog(0, nil)
+	info := newQueryInfo(nil, nil, opTypExec, "delete from users", nil)
+	db.invoke(db.ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) { return nil, nil })
+	if info.callers != nil {
+		t.Fatalf("the callers should not be captured without slow query log")
+	}
+}
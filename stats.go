@@ -0,0 +1,239 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsBuckets are the upper bounds of the latency histogram buckets, the last
+// bucket of QueryStats.Histogram counts the statements slower than all of them.
+var StatsBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStats aggregates the statements of the same fingerprint.
+type QueryStats struct {
+	Fingerprint   string        `json:"fingerprint"`
+	OpType        string        `json:"op_type"`
+	Count         int64         `json:"count"`
+	Errors        int64         `json:"errors"`
+	TotalDuration time.Duration `json:"total_duration"`
+	MinDuration   time.Duration `json:"min_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+	Histogram     []int64       `json:"histogram"`
+	// Rows is the sum of the rows returned by the selects and the rows affected by the others.
+	Rows int64 `json:"rows"`
+}
+
+// DefaultMaxFingerprints is the default max number of the fingerprints of StatsCollector.
+const DefaultMaxFingerprints = 1000
+
+// OverflowFingerprint is the fingerprint aggregating the statements of the new fingerprints
+// after the max number of the fingerprints is reached.
+const OverflowFingerprint = "<other>"
+
+// StatsCollector collects the statistics per query fingerprint. It implements http.Handler
+// and expvar.Var, so it can be served by the debug endpoints or published by expvar.Publish.
+type StatsCollector struct {
+	mu              sync.Mutex
+	stats           map[string]*QueryStats
+	maxFingerprints int
+}
+
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{stats: map[string]*QueryStats{}, maxFingerprints: DefaultMaxFingerprints}
+}
+
+func (c *StatsCollector) MaxFingerprints() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxFingerprints
+}
+
+// SetMaxFingerprints limits the number of the fingerprints, which keeps the memory bounded when
+// the queries are built with inlined values that Fingerprint can't normalize, the statements of
+// the new fingerprints are aggregated into OverflowFingerprint after the limit is reached.
+// n <= 0 means no limit, the default is DefaultMaxFingerprints.
+func (c *StatsCollector) SetMaxFingerprints(n int) {
+	c.mu.Lock()
+	c.maxFingerprints = n
+	c.mu.Unlock()
+}
+
+func (c *StatsCollector) record(info *QueryInfo) {
+	fingerprint := Fingerprint(info.Query)
+	opType := info.OpType
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.stats[fingerprint]
+	if !ok && c.maxFingerprints > 0 && len(c.stats) >= c.maxFingerprints {
+		fingerprint, opType = OverflowFingerprint, ""
+		s, ok = c.stats[fingerprint]
+	}
+	if !ok {
+		s = &QueryStats{
+			Fingerprint: fingerprint,
+			OpType:      opType,
+			MinDuration: info.Duration,
+			Histogram:   make([]int64, len(StatsBuckets)+1),
+		}
+		c.stats[fingerprint] = s
+	}
+	s.Count++
+	if info.Err != nil {
+		s.Errors++
+	}
+	s.TotalDuration += info.Duration
+	if info.Duration < s.MinDuration {
+		s.MinDuration = info.Duration
+	}
+	if info.Duration > s.MaxDuration {
+		s.MaxDuration = info.Duration
+	}
+	s.Histogram[sort.Search(len(StatsBuckets), func(i int) bool { return info.Duration <= StatsBuckets[i] })]++
+	s.Rows += info.RowsReturned + info.RowsAffected
+}
+
+// Snapshot returns a copy of the statistics sorted by the total duration in descending order.
+func (c *StatsCollector) Snapshot() []QueryStats {
+	c.mu.Lock()
+	snapshot := make([]QueryStats, 0, len(c.stats))
+	for _, s := range c.stats {
+		cp := *s
+		cp.Histogram = append([]int64(nil), s.Histogram...)
+		snapshot = append(snapshot, cp)
+	}
+	c.mu.Unlock()
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].TotalDuration > snapshot[j].TotalDuration
+	})
+	return snapshot
+}
+
+func (c *StatsCollector) Reset() {
+	c.mu.Lock()
+	c.stats = map[string]*QueryStats{}
+	c.mu.Unlock()
+}
+
+// String returns the snapshot in JSON, for expvar.
+func (c *StatsCollector) String() string {
+	data, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+func (c *StatsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(c.String()))
+}
+
+func (db *DB) StatsCollector() *StatsCollector {
+	return db.statsCollector
+}
+
+// SetStatsCollector enables collecting the statistics of the statements, nil disables it.
+func (db *DB) SetStatsCollector(c *StatsCollector) {
+	db.statsCollector = c
+}
+
+var (
+	placeholderListRegexp = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	valueListRegexp       = regexp.MustCompile(`\(\.\.\.\)(\s*,\s*\(\.\.\.\))+`)
+)
+
+// Fingerprint normalizes the query by replacing the string and number literals and the
+// placeholders with "?", collapsing the placeholder lists to "(...)", the whitespaces to
+// one space, and lowering the case except for the quoted identifiers.
+func Fingerprint(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+			continue
+		case c == '\'':
+			i = skipString(query, i)
+			c = '?'
+		case c == '"' || c == '`':
+			next := len(query)
+			if end := strings.IndexByte(query[i+1:], c); end >= 0 {
+				next = i + end + 2
+			}
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteString(query[i:next])
+			i = next
+			continue
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			for i++; i < len(query) && isDigit(query[i]); i++ {
+			}
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			for ; i < len(query) && (isDigit(query[i]) || query[i] == '.'); i++ {
+			}
+			c = '?'
+		default:
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			i++
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteByte(c)
+	}
+	s := placeholderListRegexp.ReplaceAllString(b.String(), "(...)")
+	return valueListRegexp.ReplaceAllString(s, "(...)")
+}
+
+// skipString returns the index after the string literal starting at i.
+func skipString(query string, i int) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE id = 10":                   "select * from users where id = ?",
+		"select  *\n from users where name='it''s' and x=1.5": "select * from users where name=? and x=?",
+		"select * from t2 where id in (?, ?, ?)":              "select * from t2 where id in (...)",
+		"select * from t where id in ($1,$2) and a=$3":        "select * from t where id in (...) and a=?",
+		"insert into t(a,b) values(?,?),(?,?), (?,?)":         "insert into t(a,b) values(...)",
+		"select `Name`, \"Age\" from T":                       "select `Name`, \"Age\" from t",
+	}
+	for query, want := range tests {
+		if got := Fingerprint(query); got != want {
+			t.Fatalf("Fingerprint(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestStatsCollector_SetMaxFingerprints(t *testing.T) {
+	c := NewStatsCollector()
+	c.SetMaxFingerprints(2)
+	for i := 0; i < 5; i++ {
+		c.record(&QueryInfo{OpType: OpExec, Query: fmt.Sprintf("delete from t%d", i), Duration: time.Millisecond})
+	}
+	c.record(&QueryInfo{OpType: OpExec, Query: "delete from t0", Duration: time.Millisecond})
+	counts := map[string]int64{}
+	for _, s := range c.Snapshot() {
+		counts[s.Fingerprint] = s.Count
+	}
+	if len(counts) != 3 || counts["delete from t0"] != 2 || counts["delete from t1"] != 1 || counts[OverflowFingerprint] != 3 {
+		t.Fatalf("counts: %v", counts)
+	}
+
+	c.Reset()
+	c.SetMaxFingerprints(0)
+	for i := 0; i < 5; i++ {
+		c.record(&QueryInfo{OpType: OpExec, Query: fmt.Sprintf("delete from t%d", i)})
+	}
+	if n := len(c.Snapshot()); n != 5 {
+		t.Fatalf("unlimited: %v", n)
+	}
+}
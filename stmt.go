@@ -15,6 +15,7 @@ type Stmt struct {
 	*DB
 	*sql.Stmt
 	query string
+	tx    *Tx
 }
 
 func (stmt *Stmt) Sql(ctx context.Context, dst interface{}, args ...interface{}) string {
@@ -22,8 +23,7 @@ func (stmt *Stmt) Sql(ctx context.Context, dst interface{}, args ...interface{})
 }
 
 func (stmt *Stmt) ExecContext(ctx context.Context, args ...interface{}) (Result, error) {
-	result, err := stmt.Stmt.ExecContext(ctx, args...)
-	return newResult(stmt.DB, result, stmt.query, args, false), err
+	return execContext(stmt.DB, ctx, nil, stmt, opTypExec, stmt.query, args, nil)
 }
 
 func (stmt *Stmt) Exec(args ...interface{}) (Result, error) {
@@ -31,18 +31,16 @@ func (stmt *Stmt) Exec(args ...interface{}) (Result, error) {
 }
 
 func (stmt *Stmt) QueryRowContext(ctx context.Context, dst interface{}, args ...interface{}) (Result, error) {
+	opts, args := parseQueryOptions(args)
 	if dst == nil {
 		return nil, fmt.Errorf("[sqlw %v] invalid dest value nil: %v", opTypSelect, reflect.TypeOf(dst))
 	}
 
-	rows, err := stmt.Stmt.QueryContext(ctx, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	notFound, err := rowsToStruct(rows, dst, stmt.parseFieldName, stmt.mapping, sqlMappingKey(opTypSelect, stmt.query, reflect.TypeOf(dst)), stmt.rawScan)
-	return newResult(stmt.DB, nil, stmt.query, args, notFound), err
+	return stmt.queryRows(ctx, args, func(rows *sql.Rows, info *QueryInfo) (bool, error) {
+		return info.mapRows(dst, opts, func() (bool, error) {
+			return rowsToStruct(stmt.DB, rows, dst, sqlMappingKey(opTypSelect, stmt.query, reflect.TypeOf(dst)), opts)
+		})
+	})
 }
 
 func (stmt *Stmt) QueryRow(dst interface{}, args ...interface{}) (Result, error) {
@@ -50,26 +48,29 @@ func (stmt *Stmt) QueryRow(dst interface{}, args ...interface{}) (Result, error)
 }
 
 func (stmt *Stmt) QueryContext(ctx context.Context, dst interface{}, args ...interface{}) (Result, error) {
-	rows, err := stmt.Stmt.QueryContext(ctx, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	notFound := true
-	if isStructPtr(reflect.TypeOf(dst)) {
-		notFound, err = rowsToStruct(rows, dst, stmt.parseFieldName, stmt.mapping, sqlMappingKey(opTypSelect, stmt.query, reflect.TypeOf(dst)), stmt.rawScan)
-		return newResult(stmt.DB, nil, stmt.query, args, notFound), err
-	}
-
-	notFound, err = rowsToSlice(rows, dst, stmt.parseFieldName, stmt.mapping, sqlMappingKey(opTypSelect, stmt.query, reflect.TypeOf(dst)), stmt.rawScan)
-	return newResult(stmt.DB, nil, stmt.query, args, notFound), err
+	opts, args := parseQueryOptions(args)
+	return stmt.queryRows(ctx, args, func(rows *sql.Rows, info *QueryInfo) (bool, error) {
+		return info.mapRows(dst, opts, func() (bool, error) {
+			return rowsToDst(stmt.DB, rows, dst, sqlMappingKey(opTypSelect, stmt.query, reflect.TypeOf(dst)), opts)
+		})
+	})
 }
 
 func (stmt *Stmt) Query(dst interface{}, args ...interface{}) (Result, error) {
 	return stmt.QueryContext(stmt.ctx, dst, args...)
 }
 
+func (stmt *Stmt) QueryMultiContext(ctx context.Context, dsts []interface{}, args ...interface{}) (Result, error) {
+	opts, args := parseQueryOptions(args)
+	return stmt.queryRows(ctx, args, func(rows *sql.Rows, info *QueryInfo) (bool, error) {
+		return rowsToMulti(stmt.DB, rows, info, dsts, stmt.query, opts)
+	})
+}
+
+func (stmt *Stmt) QueryMulti(dsts []interface{}, args ...interface{}) (Result, error) {
+	return stmt.QueryMultiContext(stmt.ctx, dsts, args...)
+}
+
 func (stmt *Stmt) SelectContext(ctx context.Context, dst interface{}, args ...interface{}) (Result, error) {
 	return stmt.QueryContext(ctx, dst, args...)
 }
@@ -109,14 +110,28 @@ func (stmt *Stmt) Update(args ...interface{}) (Result, error) {
 }
 
 func (stmt *Stmt) DeleteContext(ctx context.Context, args ...interface{}) (Result, error) {
-	result, err := stmt.Stmt.ExecContext(ctx, args...)
-	return newResult(stmt.DB, result, stmt.query, args, false), err
+	return execContext(stmt.DB, ctx, nil, stmt, opTypDelete, stmt.query, args, nil)
 }
 
 func (stmt *Stmt) Delete(args ...interface{}) (Result, error) {
 	return stmt.DeleteContext(stmt.ctx, args...)
 }
 
+// queryRows runs the query through the interceptors and maps the rows by f.
+func (stmt *Stmt) queryRows(ctx context.Context, args []interface{}, f func(rows *sql.Rows, info *QueryInfo) (bool, error)) (Result, error) {
+	info := newQueryInfo(nil, stmt, opTypSelect, stmt.query, args)
+	return stmt.invoke(ctx, info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		rows, err := stmt.Stmt.QueryContext(ctx, info.driverArgs...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		notFound, err := f(rows, info)
+		return newResult(stmt.DB, nil, stmt.query, info.Args, notFound), err
+	})
+}
+
 func NewStmt(db *DB, stmt *sql.Stmt, query string) *Stmt {
 	return &Stmt{
 		DB:    db,
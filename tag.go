@@ -0,0 +1,114 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagOptions is the comma-separated list following the field name in a tag,
+// e.g. "key" in `db:"user_id,key"`.
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, ""
+}
+
+// Contains reports whether the options include the flag name.
+func (o tagOptions) Contains(name string) bool {
+	_, ok := o.Get(name)
+	return ok
+}
+
+// Get returns the value of a "name=value" option, or "" for the flag name.
+func (o tagOptions) Get(name string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var opt string
+		if idx := strings.Index(s, ","); idx >= 0 {
+			opt, s = s[:idx], s[idx+1:]
+		} else {
+			opt, s = s, ""
+		}
+		opt = strings.TrimSpace(opt)
+		if opt == name {
+			return "", true
+		}
+		if strings.HasPrefix(opt, name) && len(opt) > len(name) && opt[len(name)] == '=' {
+			return opt[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// TagExtractor returns the field name and the comma separated options from the tag value.
+type TagExtractor func(value string) (name string, options string)
+
+func defaultTagExtractors() map[string]TagExtractor {
+	return map[string]TagExtractor{
+		"gorm": GormTagExtractor,
+	}
+}
+
+// GormTagExtractor parses the gorm tags like `gorm:"column:name;primaryKey"`.
+func GormTagExtractor(value string) (string, string) {
+	var name string
+	var options []string
+	for _, s := range strings.Split(value, ";") {
+		kv := strings.SplitN(strings.TrimSpace(s), ":", 2)
+		switch k := strings.TrimSpace(kv[0]); {
+		case k == "-":
+			return "-", ""
+		case strings.EqualFold(k, "column") && len(kv) == 2:
+			name = strings.TrimSpace(kv[1])
+		case strings.EqualFold(k, "primaryKey") || strings.EqualFold(k, "primary_key"):
+			options = append(options, "pk")
+		}
+	}
+	return name, strings.Join(options, ",")
+}
+
+// SetTagExtractor sets the extractor of the tag, the others are parsed as `tag:"name,options"`.
+func (db *DB) SetTagExtractor(tag string, extractor TagExtractor) {
+	extractors := map[string]TagExtractor{}
+	for k, v := range db.tagExtractors {
+		extractors[k] = v
+	}
+	extractors[tag] = extractor
+	db.tagExtractors = extractors
+	db.resetMapping()
+}
+
+// lookupTag returns the name of the first tag with a name, and the options of it and the
+// tags before it, such as "json" for `db:",json" gorm:"column:meta"`.
+func (db *DB) lookupTag(field *reflect.StructField) (string, tagOptions) {
+	var options []string
+	for _, tag := range db.tags {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		var name string
+		var opts tagOptions
+		if extractor, ok := db.tagExtractors[tag]; ok {
+			var s string
+			name, s = extractor(value)
+			opts = tagOptions(s)
+		} else {
+			name, opts = parseTag(value)
+		}
+		if opts != "" {
+			options = append(options, string(opts))
+		}
+		if name != "" {
+			return name, tagOptions(strings.Join(options, ","))
+		}
+	}
+	return "", tagOptions(strings.Join(options, ","))
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseTag(t *testing.T) {
+	name, opts := parseTag("user_id,key,layout=2006-01-02")
+	if name != "user_id" {
+		t.Fatalf("invalid name: %v", name)
+	}
+	if !opts.Contains("key") || opts.Contains("ke") || opts.Contains("layout") == false {
+		t.Fatalf("invalid options: %v", opts)
+	}
+	if v, ok := opts.Get("layout"); !ok || v != "2006-01-02" {
+		t.Fatalf("invalid layout: %v, %v", v, ok)
+	}
+
+	name, opts = parseTag("id")
+	if name != "id" || opts != "" {
+		t.Fatalf("invalid tag: %v, %v", name, opts)
+	}
+}
+
+func TestGormTagExtractor(t *testing.T) {
+	name, opts := GormTagExtractor("column:user_id;primaryKey;type:bigint")
+	if name != "user_id" || opts != "pk" {
+		t.Fatalf("invalid gorm tag: %v, %v", name, opts)
+	}
+	name, opts = GormTagExtractor("type:varchar(32)")
+	if name != "" || opts != "" {
+		t.Fatalf("invalid gorm tag: %v, %v", name, opts)
+	}
+	if name, _ = GormTagExtractor("-"); name != "-" {
+		t.Fatalf("invalid gorm tag: %v", name)
+	}
+}
+
+func TestDB_lookupTag(t *testing.T) {
+	type Model struct {
+		Id      int64  `gorm:"column:user_id;primaryKey" json:"id"`
+		Name    string `json:"name"`
+		Secret  string `json:"-"`
+		Meta    string `db:",json" gorm:"column:meta"`
+		Skipped string `db:"-" json:"skipped"`
+		Plain   string
+	}
+	db, _ := newFakeDB(t, "fakemysql")
+	db.SetTag("db", "gorm", "json")
+	typ := reflect.TypeOf(Model{})
+	tests := []struct {
+		field   string
+		name    string
+		options tagOptions
+	}{
+		{"Id", "user_id", "pk"},
+		{"Name", "name", ""},
+		{"Secret", "", ""},
+		{"Meta", "meta", "json"},
+		{"Skipped", "", ""},
+		{"Plain", "", ""},
+	}
+	for _, test := range tests {
+		field, _ := typ.FieldByName(test.field)
+		if name := db.parseFieldName(&field); name != test.name {
+			t.Fatalf("%v: name %q, want %q", test.field, name, test.name)
+		}
+		if opts := db.parseFieldOptions(&field); opts != test.options {
+			t.Fatalf("%v: options %q, want %q", test.field, opts, test.options)
+		}
+	}
+
+	db.SetTag("json", "gorm")
+	field, _ := typ.FieldByName("Id")
+	if name := db.parseFieldName(&field); name != "id" {
+		t.Fatalf("the earlier tag should win: %q", name)
+	}
+}
+
+func TestSetTagExtractor(t *testing.T) {
+	type Model struct {
+		Id   int64  `xorm:"pk 'user_id'"`
+		Name string `xorm:"'name'"`
+	}
+	db, _ := newFakeDB(t, "fakemysql")
+	db.SetTag("xorm")
+	typ := reflect.TypeOf(Model{})
+	field, _ := typ.FieldByName("Id")
+	if name := db.parseFieldName(&field); name != "pk 'user_id'" {
+		t.Fatalf("name without extractor: %q", name)
+	}
+
+	db.SetTagExtractor("xorm", func(value string) (string, string) {
+		var name string
+		var options []string
+		for _, s := range strings.Fields(value) {
+			if strings.HasPrefix(s, "'") {
+				name = strings.Trim(s, "'")
+			} else if s == "pk" {
+				options = append(options, "pk")
+			}
+		}
+		return name, strings.Join(options, ",")
+	})
+	if name, opts := db.lookupTag(&field); name != "user_id" || opts != "pk" {
+		t.Fatalf("invalid xorm tag: %q, %q", name, opts)
+	}
+	field, _ = typ.FieldByName("Name")
+	if name := db.parseFieldName(&field); name != "name" {
+		t.Fatalf("invalid xorm tag: %q", name)
+	}
+	if _, ok := db.tagExtractors["gorm"]; !ok {
+		t.Fatalf("the default extractors should be kept")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"strings"
+)
+
+// Tracer starts the spans of the statements and transactions, it can be adapted to
+// OpenTelemetry or other tracing systems.
+type Tracer interface {
+	// StartSpan starts a span parented to the span in ctx, and returns the ctx carrying the new one.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// The span attribute keys.
+const (
+	AttrStatement    = "db.statement"
+	AttrOperation    = "db.operation"
+	AttrTable        = "db.sql.table"
+	AttrRowsAffected = "db.rows_affected"
+)
+
+const (
+	spanNamePrefix = "sqlw."
+	spanNameTx     = spanNamePrefix + "tx"
+)
+
+func (db *DB) Tracer() Tracer {
+	return db.tracer
+}
+
+// SetTracer makes every statement produce a span named "sqlw.<op>", and every transaction
+// produce a "sqlw.tx" span covering Begin to Commit or Rollback.
+func (db *DB) SetTracer(tracer Tracer) {
+	db.tracer = tracer
+}
+
+// trace runs next in a span, the attributes are set after next returns so that
+// the query rewritten by the interceptors is recorded.
+func (db *DB) trace(ctx context.Context, info *QueryInfo, next Handler) (Result, error) {
+	if db.tracer == nil {
+		return next(ctx, info)
+	}
+	ctx, span := db.tracer.StartSpan(ctx, spanNamePrefix+info.OpType)
+	result, err := next(ctx, info)
+	attrs := []Attribute{
+		{Key: AttrStatement, Value: info.Query},
+		{Key: AttrOperation, Value: info.OpType},
+	}
+	if table := tableName(info.Query); table != "" {
+		attrs = append(attrs, Attribute{Key: AttrTable, Value: table})
+	}
+	if info.RowsAffected > 0 {
+		attrs = append(attrs, Attribute{Key: AttrRowsAffected, Value: info.RowsAffected})
+	}
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+	return result, err
+}
+
+// tableName returns the first table following "from", "into", "update", "join", "call" or "exec".
+func tableName(query string) string {
+	fields := strings.Fields(query)
+	for i := 0; i < len(fields)-1; i++ {
+		switch strings.ToLower(fields[i]) {
+		case "from", "into", "update", "join", "call", "exec":
+			table := fields[i+1]
+			if pos := strings.IndexAny(table, "(,;"); pos >= 0 {
+				table = table[:pos]
+			}
+			return strings.Trim(table, "`\"[]")
+		}
+	}
+	return ""
+}
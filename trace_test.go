@@ -0,0 +1,106 @@
+// Copyright 2022 lesismal. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package sqlw
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func Test_tableName(t *testing.T) {
+	tests := map[string]string{
+		"select * from users where id=?":      "users",
+		"insert into `orders`(id,uid) values": "orders",
+		"update users set name=?":             "users",
+		"delete from t;":                      "t",
+		"call transfer(?, ?)":                 "transfer",
+		"begin":                               "",
+	}
+	for query, want := range tests {
+		if got := tableName(query); got != want {
+			t.Fatalf("tableName(%v) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+type testSpanKey struct{}
+
+// testTracer records the spans as "name<-parent".
+type testTracer struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+type testSpan struct{ name string }
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {}
+func (s *testSpan) RecordError(err error)            {}
+func (s *testSpan) End()                             {}
+
+func (t *testTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	parent := "none"
+	if span, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		parent = span.name
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, name+"<-"+parent)
+	t.mu.Unlock()
+	span := &testSpan{name}
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTxSpans(t *testing.T) {
+	db, _ := newFakeDB(t, "fakemysql")
+	tracer := &testTracer{}
+	db.SetTracer(tracer)
+	requestCtx := context.WithValue(context.Background(), testSpanKey{}, &testSpan{"request"})
+	tx, err := db.BeginTx(requestCtx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Exec("delete from a")
+	tx.ExecContext(context.Background(), "delete from b")
+	tx.ExecContext(requestCtx, "delete from c")
+	childCtx, _ := tracer.StartSpan(tx.SpanContext(), "child")
+	tx.ExecContext(childCtx, "delete from d")
+	tx.Commit()
+	want := []string{
+		"sqlw.tx<-request",
+		"sqlw.begin<-sqlw.tx",
+		"sqlw.exec<-sqlw.tx",
+		"sqlw.exec<-sqlw.tx",
+		"sqlw.exec<-sqlw.tx",
+		"child<-sqlw.tx",
+		"sqlw.exec<-child",
+		"sqlw.commit<-sqlw.tx",
+	}
+	if !reflect.DeepEqual(tracer.spans, want) {
+		t.Fatalf("spans: %q", tracer.spans)
+	}
+}
+
+func TestTxWithoutSpan(t *testing.T) {
+	db, server := newFakeDB(t, "fakemysql")
+	db.SetTracer(&testTracer{})
+	sqlTx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &Tx{DB: db, Tx: sqlTx}
+	if tx.SpanContext() != db.Context() {
+		t.Fatalf("SpanContext should fall back to the ctx of DB")
+	}
+	if _, err = tx.Exec("delete from users"); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if queries := server.queries(); !reflect.DeepEqual(queries, []string{"begin", "delete from users", "commit"}) {
+		t.Fatalf("queries: %q", queries)
+	}
+}
@@ -9,14 +9,63 @@ import (
 	"database/sql"
 )
 
+var lastTxID uint64
+
 type Tx struct {
 	*DB
 	*sql.Tx
+
+	id uint64
+
+	// spanCtx is the ctx of BeginTx carrying the span covering Begin to Commit or Rollback,
+	// it's nil for the Tx not begun by DB.
+	spanCtx context.Context
+	span    Span
+}
+
+// SpanContext returns the ctx of BeginTx carrying the span of the Tx, the statements with
+// the ctx derived from it are parented to the spans in it, and the others are re-parented
+// to the span of the Tx. It returns the ctx of DB for the Tx not begun by DB.
+func (tx *Tx) SpanContext() context.Context {
+	if tx.spanCtx == nil {
+		return tx.ctx
+	}
+	return tx.spanCtx
+}
+
+// txSpanKey marks the ctx carrying the span of the Tx.
+type txSpanKey struct{}
+
+// spanContext returns ctx re-parented to the span of the Tx unless ctx is derived from
+// the ctx of the Tx, the cancellation of ctx is kept.
+func (tx *Tx) spanContext(ctx context.Context) context.Context {
+	if tx.span == nil || tx.spanCtx == nil || ctx.Value(txSpanKey{}) == tx {
+		return ctx
+	}
+	return &txContext{Context: ctx, spanCtx: tx.spanCtx}
+}
+
+// txContext looks up the values in the ctx of the Tx first, so that the tracers find the
+// span of the Tx.
+type txContext struct {
+	context.Context
+	spanCtx context.Context
+}
+
+func (c *txContext) Value(key interface{}) interface{} {
+	if v := c.spanCtx.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// ID returns the id of the Tx begun by DB, starting from 1.
+func (tx *Tx) ID() uint64 {
+	return tx.id
 }
 
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
-	result, err := tx.Tx.ExecContext(ctx, query, args...)
-	return newResult(tx.DB, result, query, args, false), err
+	return execContext(tx.DB, ctx, tx.selector(), nil, opTypExec, query, args, nil)
 }
 
 func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
@@ -24,7 +73,7 @@ func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
 }
 
 func (tx *Tx) QueryRowContext(ctx context.Context, dst interface{}, query string, args ...interface{}) (Result, error) {
-	return queryRowContext(tx.DB, ctx, tx.Tx, tx.parseFieldName, dst, tx.mapping, tx.rawScan, query, args...)
+	return queryRowContext(tx.DB, ctx, tx.selector(), dst, query, args...)
 }
 
 func (tx *Tx) QueryRow(dst interface{}, query string, args ...interface{}) (Result, error) {
@@ -32,13 +81,29 @@ func (tx *Tx) QueryRow(dst interface{}, query string, args ...interface{}) (Resu
 }
 
 func (tx *Tx) QueryContext(ctx context.Context, dst interface{}, query string, args ...interface{}) (Result, error) {
-	return queryContext(tx.DB, ctx, tx.Tx, tx.parseFieldName, dst, tx.mapping, tx.rawScan, query, args...)
+	return queryContext(tx.DB, ctx, tx.selector(), dst, query, args...)
 }
 
 func (tx *Tx) Query(dst interface{}, query string, args ...interface{}) (Result, error) {
 	return tx.QueryContext(tx.ctx, dst, query, args...)
 }
 
+func (tx *Tx) CallContext(ctx context.Context, proc string, in, out interface{}, dsts ...interface{}) (Result, error) {
+	return callContext(tx.DB, ctx, tx.selector(), proc, in, out, dsts...)
+}
+
+func (tx *Tx) Call(proc string, in, out interface{}, dsts ...interface{}) (Result, error) {
+	return tx.CallContext(tx.ctx, proc, in, out, dsts...)
+}
+
+func (tx *Tx) QueryMultiContext(ctx context.Context, dsts []interface{}, query string, args ...interface{}) (Result, error) {
+	return queryMultiContext(tx.DB, ctx, tx.selector(), dsts, query, args...)
+}
+
+func (tx *Tx) QueryMulti(dsts []interface{}, query string, args ...interface{}) (Result, error) {
+	return tx.QueryMultiContext(tx.ctx, dsts, query, args...)
+}
+
 func (tx *Tx) SelectContext(ctx context.Context, dst interface{}, query string, args ...interface{}) (Result, error) {
 	return tx.QueryContext(ctx, dst, query, args...)
 }
@@ -61,8 +126,16 @@ func (tx *Tx) Select(dst interface{}, query string, args ...interface{}) (Result
 // 	return tx.SelectOneContext(tx.ctx , dst, query, args...)
 // }
 
+func (tx *Tx) PreloadContext(ctx context.Context, dst interface{}, fields ...string) error {
+	return preloadContext(tx.DB, ctx, tx.selector(), dst, fields...)
+}
+
+func (tx *Tx) Preload(dst interface{}, fields ...string) error {
+	return tx.PreloadContext(tx.ctx, dst, fields...)
+}
+
 func (tx *Tx) InsertContext(ctx context.Context, sqlHead string, args ...interface{}) (Result, error) {
-	return insertContext(ctx, tx.Tx, nil, sqlHead, tx.DB, args...)
+	return insertContext(ctx, tx.selector(), nil, sqlHead, tx.DB, args...)
 }
 
 func (tx *Tx) Insert(sqlHead string, args ...interface{}) (Result, error) {
@@ -70,7 +143,7 @@ func (tx *Tx) Insert(sqlHead string, args ...interface{}) (Result, error) {
 }
 
 func (tx *Tx) UpdateContext(ctx context.Context, sqlHead string, args ...interface{}) (Result, error) {
-	return updateByExecContext(ctx, tx.Tx, tx.DB, nil, sqlHead, args...)
+	return updateByExecContext(ctx, tx.selector(), tx.DB, nil, sqlHead, args...)
 }
 
 func (tx *Tx) Update(sqlHead string, args ...interface{}) (Result, error) {
@@ -78,8 +151,7 @@ func (tx *Tx) Update(sqlHead string, args ...interface{}) (Result, error) {
 }
 
 func (tx *Tx) DeleteContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
-	result, err := tx.Tx.ExecContext(ctx, query, args...)
-	return newResult(tx.DB, result, query, args, false), err
+	return execContext(tx.DB, ctx, tx.selector(), nil, opTypDelete, query, args, nil)
 }
 
 func (tx *Tx) Delete(query string, args ...interface{}) (Result, error) {
@@ -87,25 +159,43 @@ func (tx *Tx) Delete(query string, args ...interface{}) (Result, error) {
 }
 
 func (tx *Tx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
-	stmt, err := tx.Tx.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	return NewStmt(tx.DB, stmt, query), nil
+	return prepareContext(tx.DB, ctx, tx.selector(), tx.Tx, query)
 }
 
 func (tx *Tx) Prepare(query string) (*Stmt, error) {
-	stmt, err := tx.Tx.Prepare(query)
-	if err != nil {
-		return nil, err
-	}
-	return NewStmt(tx.DB, stmt, query), nil
+	return tx.PrepareContext(tx.ctx, query)
 }
 
 func (tx *Tx) StmtContext(ctx context.Context, stmt *Stmt) *Stmt {
-	return NewStmt(tx.DB, tx.Tx.StmtContext(ctx, stmt.Stmt), stmt.query)
+	s := NewStmt(tx.DB, tx.Tx.StmtContext(ctx, stmt.Stmt), stmt.query)
+	s.tx = tx
+	return s
 }
 
 func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
-	return NewStmt(tx.DB, tx.Tx.Stmt(stmt.Stmt), stmt.query)
+	return tx.StmtContext(tx.ctx, stmt)
+}
+
+func (tx *Tx) Commit() error {
+	return tx.end(opTypCommit, tx.Tx.Commit)
+}
+
+func (tx *Tx) Rollback() error {
+	return tx.end(opTypRollback, tx.Tx.Rollback)
+}
+
+func (tx *Tx) end(opType string, f func() error) error {
+	info := newQueryInfo(tx.selector(), nil, opType, opType, nil)
+	_, err := tx.invoke(tx.SpanContext(), info, func(ctx context.Context, info *QueryInfo) (Result, error) {
+		return nil, f()
+	})
+	if span := tx.span; span != nil && err != sql.ErrTxDone {
+		tx.span = nil
+		span.SetAttributes(Attribute{Key: AttrOperation, Value: opType})
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+	return err
 }